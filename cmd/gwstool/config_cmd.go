@@ -30,6 +30,8 @@ var configShowCmd = &cobra.Command{
 					"client_cert": config.ClientCert,
 					"client_key":  config.ClientKey,
 					"timeout":     config.Timeout,
+					"proxy":       config.Proxy,
+					"pkcs12_file": config.PKCS12File,
 				}
 			}
 			outputResult(result)
@@ -58,6 +60,15 @@ var configShowCmd = &cobra.Command{
 				fmt.Printf("  Client Cert: %s\n", config.ClientCert)
 				fmt.Printf("  Client Key: %s\n", config.ClientKey)
 				fmt.Printf("  Timeout: %d seconds\n", config.Timeout)
+				if config.Proxy != "" {
+					fmt.Printf("  Proxy: %s\n", config.Proxy)
+				}
+				if config.PKCS12File != "" {
+					fmt.Printf("  PKCS#12 File: %s\n", config.PKCS12File)
+				}
+				if config.ClientCertPEM != "" {
+					fmt.Printf("  Client Cert/Key: PEM from environment\n")
+				}
 			}
 		}
 		return nil
@@ -109,6 +120,10 @@ client_cert=/path/to/client.cert
 # Path to client private key file
 client_key=/path/to/client.key
 
+# Or a PKCS#12 bundle instead of client_cert and client_key
+#pkcs12_file=/path/to/client.p12
+#pkcs12_password_env=GWS_PKCS12_PASSWORD
+
 # Request timeout in seconds
 timeout=30
 `
@@ -188,16 +203,22 @@ var configValidateCmd = &cobra.Command{
 		// Validate required fields
 		errors := []string{}
 
-		if cfg.ClientCert == "" {
-			errors = append(errors, "client_cert is required")
-		} else if !fileExists(cfg.ClientCert) {
-			errors = append(errors, fmt.Sprintf("client_cert file not found: %s", cfg.ClientCert))
-		}
+		if cfg.PKCS12File != "" {
+			if !fileExists(cfg.PKCS12File) {
+				errors = append(errors, fmt.Sprintf("pkcs12_file not found: %s", cfg.PKCS12File))
+			}
+		} else if cfg.ClientCertPEM == "" || cfg.ClientKeyPEM == "" {
+			if cfg.ClientCert == "" {
+				errors = append(errors, "client_cert is required")
+			} else if !fileExists(cfg.ClientCert) {
+				errors = append(errors, fmt.Sprintf("client_cert file not found: %s", cfg.ClientCert))
+			}
 
-		if cfg.ClientKey == "" {
-			errors = append(errors, "client_key is required")
-		} else if !fileExists(cfg.ClientKey) {
-			errors = append(errors, fmt.Sprintf("client_key file not found: %s", cfg.ClientKey))
+			if cfg.ClientKey == "" {
+				errors = append(errors, "client_key is required")
+			} else if !fileExists(cfg.ClientKey) {
+				errors = append(errors, fmt.Sprintf("client_key file not found: %s", cfg.ClientKey))
+			}
 		}
 
 		if cfg.CAFile != "" && !fileExists(cfg.CAFile) {
@@ -1,8 +1,6 @@
 module github.com/uwit-ue/uw-gws-client-go/cmd/gwstool
 
-go 1.24
-
-toolchain go1.24.5
+go 1.25.0
 
 require (
 	github.com/spf13/cobra v1.9.1
@@ -13,7 +11,9 @@ require (
 	github.com/go-resty/resty/v2 v2.16.5 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/crypto v0.51.0 // indirect
+	golang.org/x/net v0.55.0 // indirect
+	software.sslmate.com/src/go-pkcs12 v0.7.3 // indirect
 )
 
 // Use local version of the library
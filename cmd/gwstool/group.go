@@ -144,6 +144,7 @@ var groupUpdateCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		original := group.Clone()
 
 		if interactive {
 			if displayName := promptForInput(fmt.Sprintf("Display Name [%s]", group.DisplayName)); displayName != "" {
@@ -220,6 +221,11 @@ var groupUpdateCmd = &cobra.Command{
 			return fmt.Errorf("at least one admin must remain. Cannot remove all admins")
 		}
 
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			outputResult(gws.DiffGroups(original, group))
+			return nil
+		}
+
 		updatedGroup, err := gwsClient.UpdateGroup(group)
 		if err != nil {
 			return err
@@ -374,6 +380,33 @@ var groupMoveOnlyStemCmd = &cobra.Command{
 	},
 }
 
+var groupNestingCmd = &cobra.Command{
+	Use:   "nesting <group-id|stem>",
+	Short: "Analyze nested group cycles and depth",
+	Long:  "Build the graph of groups nested under a group, or under all groups in a stem with --stem, and report cycles, depth, fan-out and groups included through several paths",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stem, _ := cmd.Flags().GetBool("stem")
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		maxPaths, _ := cmd.Flags().GetInt("max-paths")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		opts := &gws.NestingOptions{MaxDepth: maxDepth, MaxPaths: maxPaths, Concurrency: concurrency}
+
+		var report *gws.NestingReport
+		var err error
+		if stem {
+			report, err = gwsClient.AnalyzeStemNesting(args[0], opts)
+		} else {
+			report, err = gwsClient.AnalyzeNesting(args[0], opts)
+		}
+		if err != nil {
+			return err
+		}
+		outputResult(report)
+		return nil
+	},
+}
+
 var groupHistoryCmd = &cobra.Command{
 	Use:   "history <group-id>",
 	Short: "Get group history",
@@ -424,6 +457,7 @@ func init() {
 	groupCmd.AddCommand(groupHistoryCmd)
 	groupCmd.AddCommand(groupRenameCmd)
 	groupCmd.AddCommand(groupMoveOnlyStemCmd)
+	groupCmd.AddCommand(groupNestingCmd)
 
 	// Flags for create command
 	groupCreateCmd.Flags().String("display-name", "", "Display name for the group")
@@ -455,6 +489,8 @@ func init() {
 	groupUpdateCmd.Flags().Bool("remove-all-creators", false, "Remove all creator entities")
 	groupUpdateCmd.Flags().Bool("remove-all-readers", false, "Remove all reader entities")
 
+	groupUpdateCmd.Flags().Bool("dry-run", false, "Show the changes that would be made without saving them")
+
 	// Flags for delete command
 	groupDeleteCmd.Flags().Bool("confirm", false, "Confirm deletion without prompting")
 
@@ -473,4 +509,10 @@ func init() {
 	// Flags for move command
 	groupMoveOnlyStemCmd.Flags().String("new-stem", "", "New stem (path prefix) for the group")
 	groupMoveOnlyStemCmd.Flags().Bool("show", false, "After moving, fetch and show the updated group (uses regid)")
+
+	// Flags for nesting command
+	groupNestingCmd.Flags().Bool("stem", false, "Treat the argument as a stem and analyze all groups under it")
+	groupNestingCmd.Flags().Int("max-depth", 0, "Maximum number of group levels to read (0 for unlimited)")
+	groupNestingCmd.Flags().Int("max-paths", 0, "Maximum number of inclusion paths to list per group (0 for 10)")
+	groupNestingCmd.Flags().Int("concurrency", 4, "Number of group memberships to fetch at once")
 }
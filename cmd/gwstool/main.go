@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +23,16 @@ type Config struct {
 	ClientCert string
 	ClientKey  string
 	Timeout    int
+	Proxy      string
+
+	// PKCS12File and PKCS12Password give the credentials as a .p12/.pfx bundle
+	PKCS12File     string
+	PKCS12Password string
+
+	// ClientCertPEM, ClientKeyPEM and CAPEM hold PEM data read from environment variables
+	ClientCertPEM string
+	ClientKeyPEM  string
+	CAPEM         string
 }
 
 var (
@@ -79,6 +90,18 @@ func initializeClient() error {
 		ClientCert: config.ClientCert,
 		ClientKey:  config.ClientKey,
 		Timeout:    30,
+		Retry:      gws.DefaultRetryPolicy(),
+		Proxy:      config.Proxy,
+
+		PKCS12File:     config.PKCS12File,
+		PKCS12Password: config.PKCS12Password,
+	}
+	if config.ClientCertPEM != "" || config.ClientKeyPEM != "" {
+		gwsConfig.ClientCertPEM = []byte(config.ClientCertPEM)
+		gwsConfig.ClientKeyPEM = []byte(config.ClientKeyPEM)
+	}
+	if config.CAPEM != "" {
+		gwsConfig.CAPEM = []byte(config.CAPEM)
 	}
 
 	if config.Timeout > 0 {
@@ -145,6 +168,20 @@ func loadConfig() (*Config, error) {
 			if timeout, err := strconv.Atoi(value); err == nil {
 				cfg.Timeout = timeout
 			}
+		case "proxy":
+			cfg.Proxy = value
+		case "pkcs12_file":
+			cfg.PKCS12File = value
+		case "pkcs12_password":
+			cfg.PKCS12Password = value
+		case "pkcs12_password_env":
+			cfg.PKCS12Password = os.Getenv(value)
+		case "client_cert_pem_env":
+			cfg.ClientCertPEM = os.Getenv(value)
+		case "client_key_pem_env":
+			cfg.ClientKeyPEM = os.Getenv(value)
+		case "ca_pem_env":
+			cfg.CAPEM = os.Getenv(value)
 		}
 	}
 
@@ -153,13 +190,20 @@ func loadConfig() (*Config, error) {
 	}
 
 	// Validate that required credentials are present
-	if cfg.ClientCert == "" || cfg.ClientKey == "" {
-		return nil, fmt.Errorf("configuration file %s must contain both client_cert and client_key for authentication", configPath)
+	if !cfg.hasCredentials() {
+		return nil, fmt.Errorf("configuration file %s must contain client_cert and client_key, pkcs12_file, or client_cert_pem_env and client_key_pem_env for authentication", configPath)
 	}
 
 	return cfg, nil
 }
 
+// hasCredentials reports whether one complete form of client credentials is configured.
+func (c *Config) hasCredentials() bool {
+	return (c.ClientCert != "" && c.ClientKey != "") ||
+		c.PKCS12File != "" ||
+		(c.ClientCertPEM != "" && c.ClientKeyPEM != "")
+}
+
 func getConfigPath() string {
 	if configFile != "" {
 		return configFile
@@ -346,6 +390,14 @@ func outputResult(data interface{}) {
 			} else {
 				fmt.Println("No history entries found")
 			}
+		case *gws.NestingReport:
+			printNestingReport(v)
+		case gws.GroupDiff:
+			if v.IsEmpty() {
+				fmt.Println("No changes")
+			} else {
+				fmt.Print(v)
+			}
 		case *gws.MemberList:
 			if v != nil {
 				for _, member := range *v {
@@ -371,6 +423,65 @@ func outputResult(data interface{}) {
 	}
 }
 
+func printNestingReport(r *gws.NestingReport) {
+	fmt.Printf("Roots: %s\n", strings.Join(r.Roots, ", "))
+	fmt.Printf("Groups analyzed: %d\n", len(r.Groups))
+	fmt.Printf("Max depth: %d\n", r.MaxDepth)
+	if len(r.DeepestPath) > 1 {
+		fmt.Printf("Deepest path: %s\n", strings.Join(r.DeepestPath, " -> "))
+	}
+
+	fmt.Printf("\nCycles: %d\n", len(r.Cycles))
+	for _, cycle := range r.Cycles {
+		fmt.Printf("  %s\n", strings.Join(cycle, " -> "))
+	}
+
+	fanOut := make([]string, 0, len(r.FanOut))
+	for gid := range r.FanOut {
+		fanOut = append(fanOut, gid)
+	}
+	sort.Slice(fanOut, func(i, j int) bool {
+		if r.FanOut[fanOut[i]] != r.FanOut[fanOut[j]] {
+			return r.FanOut[fanOut[i]] > r.FanOut[fanOut[j]]
+		}
+		return fanOut[i] < fanOut[j]
+	})
+	fmt.Printf("\nFan-out (nested groups per group):\n")
+	if len(fanOut) == 0 {
+		fmt.Println("  None")
+	}
+	for _, gid := range fanOut {
+		fmt.Printf("  %-50s %d\n", gid, r.FanOut[gid])
+	}
+
+	multi := make([]string, 0, len(r.MultiPath))
+	for gid := range r.MultiPath {
+		multi = append(multi, gid)
+	}
+	sort.Strings(multi)
+	fmt.Printf("\nGroups included through multiple paths: %d\n", len(multi))
+	for _, gid := range multi {
+		if n := r.PathCount[gid]; n > len(r.MultiPath[gid]) {
+			fmt.Printf("  %s (%d paths, showing %d)\n", gid, n, len(r.MultiPath[gid]))
+		} else {
+			fmt.Printf("  %s\n", gid)
+		}
+		for _, path := range r.MultiPath[gid] {
+			fmt.Printf("    %s\n", strings.Join(path, " -> "))
+		}
+	}
+
+	if len(r.Truncated) > 0 {
+		fmt.Printf("\nNot expanded (max depth): %s\n", strings.Join(r.Truncated, ", "))
+	}
+	if len(r.Errors) > 0 {
+		fmt.Printf("\nUnreadable groups:\n")
+		for gid, msg := range r.Errors {
+			fmt.Printf("  %s: %s\n", gid, msg)
+		}
+	}
+}
+
 func promptForInput(prompt string) string {
 	fmt.Print(prompt + ": ")
 	scanner := bufio.NewScanner(os.Stdin)
@@ -2,6 +2,12 @@ module github.com/uwit-ue/uw-gws-client-go
 
 go 1.25.0
 
-require github.com/go-resty/resty/v2 v2.16.5
+require (
+	github.com/go-resty/resty/v2 v2.16.5
+	software.sslmate.com/src/go-pkcs12 v0.7.3
+)
 
-require golang.org/x/net v0.55.0 // indirect
+require (
+	golang.org/x/crypto v0.51.0 // indirect
+	golang.org/x/net v0.55.0 // indirect
+)
@@ -2,9 +2,16 @@
 package gws
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -14,20 +21,120 @@ import (
 type Config struct {
 	APIUrl        string
 	Timeout       time.Duration
-	Synchronized  bool // When true, API writes wait for cache propagation before returning
+	Synchronized  bool // When true, API writes wait for cache propagation before returning; see WithSynchronized for per-call control
 	SkipTLSVerify bool
 	CAFile        string
 	ClientCert    string
 	ClientKey     string
+
+	// ClientCertPEM and ClientKeyPEM supply the client certificate and key as PEM data
+	// instead of files.
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+
+	// PKCS12File is a PKCS#12 (.p12 or .pfx) bundle holding the client certificate, its key
+	// and any intermediate certificates. PKCS12Password decrypts it.
+	PKCS12File     string
+	PKCS12Password string
+
+	// Certificate is a ready client certificate, for example one decoded with ParsePKCS12.
+	// Only one of ClientCert, ClientCertPEM, PKCS12File, Certificate and CertificateSource
+	// may be set.
+	Certificate *tls.Certificate
+
+	// CAPEM supplies CA certificates as PEM data, in addition to any in CAFile.
+	CAPEM []byte
+
+	// RootCAs replaces the system roots for verifying the API server. CAFile and CAPEM
+	// are added to a copy of it.
+	RootCAs *x509.CertPool
+
+	// Retry controls retries of transient failures. Nil disables retries.
+	Retry *RetryPolicy
+
+	// RateLimit caps requests per second across the client, zero means unlimited.
+	// RateBurst is how many requests may be sent at once before the limit applies.
+	// The rate is lowered automatically when the API answers 429 Too Many Requests.
+	RateLimit float64
+	RateBurst int
+
+	// MaxInFlight caps the number of concurrent requests, zero means unlimited.
+	MaxInFlight int
+
+	// MemberChunkConcurrency is how many chunks of a long AddMembers or DeleteMembers
+	// call are sent at once, zero or one means one after another.
+	MemberChunkConcurrency int
+
+	// Logger receives a record of each request and retry, nil disables logging.
+	// Member ID lists are only logged at debug level and the client key is never logged.
+	Logger *slog.Logger
+
+	// Middleware wraps every client operation, outermost first. See also Client.Use.
+	Middleware []Middleware
+
+	// HTTPClient is used to send requests instead of one built by the client. It is copied,
+	// so the caller's client is left unchanged. Timeout, when not zero, overrides its timeout.
+	HTTPClient *http.Client
+
+	// Transport replaces the round tripper of the HTTP client, for example with a recording
+	// transport in tests. An *http.Transport, here or in HTTPClient, is cloned before the TLS,
+	// proxy and pool settings below are applied. Those settings need an *http.Transport; with
+	// any other round tripper they must be left empty and set on the round tripper instead.
+	Transport http.RoundTripper
+
+	// Proxy is the URL of the HTTP proxy to send requests through. Empty uses the proxy
+	// given by the HTTPS_PROXY and NO_PROXY environment variables, if any.
+	Proxy string
+
+	// MaxIdleConns caps the keep-alive connections held open to the API, zero keeps the
+	// transport's default. The API is a single host, so the cap also applies per host.
+	MaxIdleConns int
+
+	// IdleConnTimeout is how long an idle keep-alive connection is held open, zero keeps
+	// the transport's default.
+	IdleConnTimeout time.Duration
+
+	// CertificateSource supplies the client certificate in place of ClientCert and ClientKey.
+	CertificateSource CertificateSource
+
+	// CertReloadInterval reloads ClientCert and ClientKey this often even if the files look
+	// unchanged. Changed files are reloaded at the next new connection regardless.
+	CertReloadInterval time.Duration
+
+	// CertExpiryWarning is how close to expiry the client certificate gets before a warning
+	// is logged to Logger. Zero means 14 days, negative disables the warning.
+	CertExpiryWarning time.Duration
+
+	// Cache enables a read-through cache of groups and memberships, nil disables it.
+	Cache *CacheConfig
+
+	// ModifyAttempts is how many times ModifyGroup tries to apply its change when the group
+	// keeps being changed by someone else, zero means 5.
+	ModifyAttempts int
 }
 
 // Client wraps resty.Client
 type Client struct {
 	resty      *resty.Client
+	transport  *http.Transport // set once wrapped by limitTransport or logTransport
 	config     *Config
 	configured bool
 	once       sync.Once
 	configErr  error
+
+	// synchronized is the default synchronized mode for writes, seeded from Config
+	synchronized atomic.Bool
+
+	// certSource supplies the client certificate, certChecked is the last one checked for expiry
+	certSource  CertificateSource
+	certChecked atomic.Pointer[tls.Certificate]
+
+	// middleware wraps operations, seeded from Config and extended by Use
+	mwMu       sync.RWMutex
+	middleware []Middleware
+
+	// cache holds read results, nil when Config.Cache is nil
+	cache *cache
 }
 
 // DefaultConfig constructs a basic Config object
@@ -37,6 +144,7 @@ func DefaultConfig() *Config {
 		Timeout:       30,
 		SkipTLSVerify: false,
 		Synchronized:  false,
+		Retry:         DefaultRetryPolicy(),
 	}
 	return dc
 }
@@ -46,13 +154,38 @@ func NewClient(config *Config) (*Client, error) {
 	if config == nil {
 		return nil, errors.New("config cannot be nil")
 	}
-	restyInst := resty.New()
+	var restyInst *resty.Client
+	if config.HTTPClient != nil {
+		hc := *config.HTTPClient
+		restyInst = resty.NewWithClient(&hc)
+	} else {
+		restyInst = resty.New()
+	}
+	if config.Transport != nil {
+		restyInst.SetTransport(config.Transport)
+	}
+	if t, err := restyInst.Transport(); err == nil && (config.HTTPClient != nil || config.Transport != nil) {
+		// Settings applied below must not leak into the caller's transport
+		restyInst.SetTransport(t.Clone())
+	}
 	c := &Client{resty: restyInst, config: config}
+	c.synchronized.Store(config.Synchronized)
+	c.middleware = append([]Middleware(nil), config.Middleware...)
+	c.cache = newCache(config.Cache)
 	// Prepare static headers early
 	restyInst.SetHeader("Accept", "application/json")
 	restyInst.SetHeader("Content-Type", "application/json")
+	restyInst.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		recordStatus(resp)
+		return nil
+	})
 	// Defer TLS & the rest to lazy configure via sync.Once
-	c.resty.SetTLSClientConfig(&tls.Config{Renegotiation: tls.RenegotiateFreelyAsClient})
+	if t, err := restyInst.Transport(); err == nil {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+	}
 	// Trigger initial configure so early errors are caught
 	if _, err := c.healthCheckConfigure(); err != nil {
 		return nil, err
@@ -72,34 +205,114 @@ func (client *Client) configure() {
 		cfg := client.config
 		// Basic settings
 		restyInst.SetBaseURL(cfg.APIUrl)
-		restyInst.SetTimeout(cfg.Timeout * time.Second)
+		if cfg.Timeout != 0 || cfg.HTTPClient == nil {
+			restyInst.SetTimeout(cfg.Timeout * time.Second)
+		}
 		restyInst.SetError(errorResponse{})
+		cfg.Retry.apply(restyInst)
+		if err := client.configureTransport(); err != nil {
+			client.configErr = err
+			return
+		}
 		// TLS setup
-		if cfg.CAFile != "" {
-			restyInst.SetRootCertificate(cfg.CAFile)
+		if err := client.configureTLS(); err != nil {
+			client.configErr = err
+			return
 		}
-		if cfg.ClientCert != "" || cfg.ClientKey != "" {
-			cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
-			if err != nil {
+		if cfg.RateLimit > 0 || cfg.MaxInFlight > 0 || cfg.Logger != nil {
+			if err := client.wrapTransport(); err != nil {
 				client.configErr = err
 				return
 			}
-			restyInst.SetCertificates(cert)
+		}
+		if cfg.Logger != nil {
+			client.installLogger(cfg.Logger)
 		}
 		restyInst.SetDebug(false)
 		client.configured = true
 	})
 }
 
-// SetTLSClientConfig assigns client TLS config
+// configureTransport applies the proxy and connection pool settings, and checks that the
+// TLS settings have an *http.Transport to go to.
+func (client *Client) configureTransport() error {
+	cfg := client.config
+	if cfg.Proxy == "" && cfg.MaxIdleConns == 0 && cfg.IdleConnTimeout == 0 && !cfg.hasTLSSettings() {
+		return nil
+	}
+	transport, err := client.resty.Transport()
+	if err != nil {
+		return fmt.Errorf("TLS, proxy and connection pool settings need an *http.Transport: %w", err)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	return nil
+}
+
+// wrapTransport wraps the HTTP transport with the configured rate limit, concurrency cap
+// and request logging.
+func (client *Client) wrapTransport() error {
+	rt := client.resty.GetClient().Transport
+	transport, _ := rt.(*http.Transport)
+	if client.config.RateLimit > 0 || client.config.MaxInFlight > 0 {
+		lt := &limitTransport{next: rt}
+		if client.config.RateLimit > 0 {
+			lt.limiter = newRateLimiter(client.config.RateLimit, client.config.RateBurst)
+		}
+		if client.config.MaxInFlight > 0 {
+			lt.inFlight = make(chan struct{}, client.config.MaxInFlight)
+		}
+		rt = lt
+	}
+	if client.config.Logger != nil {
+		// Outermost, so logged durations include time spent waiting on the limits
+		rt = &logTransport{next: rt, logger: client.config.Logger}
+	}
+	client.transport = transport
+	client.resty.SetTransport(rt)
+	return nil
+}
+
+// SetTLSClientConfig assigns client TLS config. Unless c has certificates of its own, the
+// client certificate from Config keeps being presented.
 func (client *Client) SetTLSClientConfig(c *tls.Config) {
+	if client.certSource != nil && c != nil && c.GetClientCertificate == nil && len(c.Certificates) == 0 {
+		c = c.Clone()
+		c.GetClientCertificate = client.getClientCertificate
+	}
+	if client.transport != nil {
+		// resty can only reach the TLS config of a bare *http.Transport
+		client.transport.TLSClientConfig = c
+		return
+	}
 	client.resty.SetTLSClientConfig(c)
 }
 
-// request returns new resty.Request from configured client
-func (client *Client) request() *resty.Request {
+// request returns new resty.Request from configured client, bound to ctx so that
+// cancellation and deadlines propagate to the underlying HTTP request.
+func (client *Client) request(ctx context.Context) *resty.Request {
 	client.configure()
-	return client.resty.R()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req := client.resty.R().SetContext(ctx)
+	applyOperation(ctx, req)
+	return req
 }
 
 // ConfigError returns any configuration error encountered during lazy initialization.
@@ -109,23 +322,20 @@ func (client *Client) ConfigError() error { return client.configErr }
 // (create, update, delete) will not return until the changes have propagated to the
 // API's read cache. This ensures that subsequent read operations will immediately see
 // the changes, but may result in slower write operations.
+//
+// Deprecated: the setting applies to every goroutine sharing the Client. Pass
+// WithSynchronized to individual write calls instead.
 func (client *Client) EnableSynchronized() {
-	client.config.Synchronized = true
+	client.synchronized.Store(true)
 }
 
 // DisableSynchronized disables synchronized API operations (default behavior).
 // Write operations will return immediately after being processed, but changes may not
 // be visible in read operations until the cache is updated. This provides better
 // performance but requires applications to handle eventual consistency.
+//
+// Deprecated: the setting applies to every goroutine sharing the Client. Pass
+// WithSynchronized to individual write calls instead.
 func (client *Client) DisableSynchronized() {
-	client.config.Synchronized = false
-}
-
-// syncQueryString returns the desired synchronized mode as a query string.
-func (client *Client) syncQueryString() string {
-	if client.config.Synchronized {
-		// Value doesn't matter, only presence/absence
-		return "synchronized=true"
-	}
-	return ""
+	client.synchronized.Store(false)
 }
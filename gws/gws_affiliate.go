@@ -0,0 +1,247 @@
+package gws
+
+import (
+	"context"
+	"fmt"
+)
+
+// Affiliate names
+const (
+	AffiliateEmail   Affiliate = "email"
+	AffiliateGoogle  Affiliate = "google"
+	AffiliateUWNetID Affiliate = "uwnetid"
+	AffiliateRadius  Affiliate = "radius"
+)
+
+// AffiliateStatus is the activation status of a group affiliate.
+// enum [ active, inactive ]
+type AffiliateStatus string
+
+// Affiliate statuses
+const (
+	AffiliateStatusActive   AffiliateStatus = "active"
+	AffiliateStatusInactive AffiliateStatus = "inactive"
+)
+
+// Google Groups sender choices
+const (
+	GoogleSenderNone    GoogleSenderString = "none"
+	GoogleSenderAll     GoogleSenderString = "all"
+	GoogleSenderMembers GoogleSenderString = "members"
+	GoogleSenderUW      GoogleSenderString = "uw"
+)
+
+// EmailAffiliate is the Exchange email affiliate of a group.
+type EmailAffiliate struct {
+	// Status of the affiliate. Enum [ active, inactive ]
+	Status AffiliateStatus
+
+	// Senders allowed to send to the group address
+	Senders EmailSendersString
+}
+
+// GoogleAffiliate is the Google Groups affiliate of a group.
+type GoogleAffiliate struct {
+	// Status of the affiliate. Enum [ active, inactive ]
+	Status AffiliateStatus
+
+	// Sender who may post to the Google group. Enum [ none, all, members, uw ]
+	Sender GoogleSenderString
+}
+
+// RadiusAffiliate is the RADIUS affiliate of a group.
+type RadiusAffiliate struct {
+	// Status of the affiliate. Enum [ active, inactive ]
+	Status AffiliateStatus
+}
+
+// affiliateData is an affiliate as exchanged with the API.
+type affiliateData struct {
+	// Name of the affiliate. Enum [ email, google, uwnetid, radius ]
+	Name Affiliate `json:"name"`
+
+	// Status of the affiliate. Enum [ active, inactive ]
+	Status AffiliateStatus `json:"status"`
+
+	// Sender senders list (email) or sender keyword (google)
+	Sender string `json:"sender,omitempty"`
+}
+
+// affiliateResponse is what comes back when asking for a group affiliate.
+type affiliateResponse struct {
+	// Schema The schema in use. Enum [ "urn:mace:washington.edu:schemas:groups:1.0" ]
+	Schemas []string
+
+	// Meta Affiliate metadata
+	Meta struct {
+		// resourceType enum [ affiliate ]
+		ResourceType string
+
+		// Version API version
+		Version string
+
+		// RegID the regid of the Group
+		RegID string
+
+		// ID the ID of the group
+		ID string
+
+		// Timestamp Response timestamp (milli-seconds from epoch)
+		Timestamp int64
+	}
+
+	// Data the affiliate
+	Data affiliateData
+}
+
+// GetEmailAffiliate returns the Exchange email affiliate of the group.
+func (client *Client) GetEmailAffiliate(groupid string) (*EmailAffiliate, error) {
+	return client.GetEmailAffiliateContext(context.Background(), groupid)
+}
+
+// GetEmailAffiliateContext is like GetEmailAffiliate but carries ctx through to the API request.
+func (client *Client) GetEmailAffiliateContext(ctx context.Context, groupid string) (*EmailAffiliate, error) {
+	return invokeResult(client, ctx, "GetEmailAffiliate", groupid, func(ctx context.Context) (*EmailAffiliate, error) {
+		a, err := client.getAffiliate(ctx, groupid, AffiliateEmail)
+		if err != nil {
+			return nil, err
+		}
+		return &EmailAffiliate{Status: a.Status, Senders: EmailSendersString(a.Sender)}, nil
+	})
+}
+
+// PutEmailAffiliate creates or updates the Exchange email affiliate of the group.
+func (client *Client) PutEmailAffiliate(groupid string, affiliate *EmailAffiliate, opts ...RequestOption) error {
+	return client.PutEmailAffiliateContext(context.Background(), groupid, affiliate, opts...)
+}
+
+// PutEmailAffiliateContext is like PutEmailAffiliate but carries ctx through to the API request.
+func (client *Client) PutEmailAffiliateContext(ctx context.Context, groupid string, affiliate *EmailAffiliate, opts ...RequestOption) error {
+	return client.invoke(ctx, "PutEmailAffiliate", groupid, func(ctx context.Context) error {
+		return client.putAffiliate(ctx, groupid, AffiliateEmail, affiliate.Status, string(affiliate.Senders), opts)
+	})
+}
+
+// GetGoogleAffiliate returns the Google Groups affiliate of the group.
+func (client *Client) GetGoogleAffiliate(groupid string) (*GoogleAffiliate, error) {
+	return client.GetGoogleAffiliateContext(context.Background(), groupid)
+}
+
+// GetGoogleAffiliateContext is like GetGoogleAffiliate but carries ctx through to the API request.
+func (client *Client) GetGoogleAffiliateContext(ctx context.Context, groupid string) (*GoogleAffiliate, error) {
+	return invokeResult(client, ctx, "GetGoogleAffiliate", groupid, func(ctx context.Context) (*GoogleAffiliate, error) {
+		a, err := client.getAffiliate(ctx, groupid, AffiliateGoogle)
+		if err != nil {
+			return nil, err
+		}
+		return &GoogleAffiliate{Status: a.Status, Sender: GoogleSenderString(a.Sender)}, nil
+	})
+}
+
+// PutGoogleAffiliate creates or updates the Google Groups affiliate of the group.
+func (client *Client) PutGoogleAffiliate(groupid string, affiliate *GoogleAffiliate, opts ...RequestOption) error {
+	return client.PutGoogleAffiliateContext(context.Background(), groupid, affiliate, opts...)
+}
+
+// PutGoogleAffiliateContext is like PutGoogleAffiliate but carries ctx through to the API request.
+func (client *Client) PutGoogleAffiliateContext(ctx context.Context, groupid string, affiliate *GoogleAffiliate, opts ...RequestOption) error {
+	return client.invoke(ctx, "PutGoogleAffiliate", groupid, func(ctx context.Context) error {
+		switch affiliate.Sender {
+		case "", GoogleSenderNone, GoogleSenderAll, GoogleSenderMembers, GoogleSenderUW:
+		default:
+			return fmt.Errorf("invalid google sender %q: must be none, all, members or uw", affiliate.Sender)
+		}
+		return client.putAffiliate(ctx, groupid, AffiliateGoogle, affiliate.Status, string(affiliate.Sender), opts)
+	})
+}
+
+// GetRadiusAffiliate returns the RADIUS affiliate of the group.
+func (client *Client) GetRadiusAffiliate(groupid string) (*RadiusAffiliate, error) {
+	return client.GetRadiusAffiliateContext(context.Background(), groupid)
+}
+
+// GetRadiusAffiliateContext is like GetRadiusAffiliate but carries ctx through to the API request.
+func (client *Client) GetRadiusAffiliateContext(ctx context.Context, groupid string) (*RadiusAffiliate, error) {
+	return invokeResult(client, ctx, "GetRadiusAffiliate", groupid, func(ctx context.Context) (*RadiusAffiliate, error) {
+		a, err := client.getAffiliate(ctx, groupid, AffiliateRadius)
+		if err != nil {
+			return nil, err
+		}
+		return &RadiusAffiliate{Status: a.Status}, nil
+	})
+}
+
+// PutRadiusAffiliate creates or updates the RADIUS affiliate of the group.
+func (client *Client) PutRadiusAffiliate(groupid string, affiliate *RadiusAffiliate, opts ...RequestOption) error {
+	return client.PutRadiusAffiliateContext(context.Background(), groupid, affiliate, opts...)
+}
+
+// PutRadiusAffiliateContext is like PutRadiusAffiliate but carries ctx through to the API request.
+func (client *Client) PutRadiusAffiliateContext(ctx context.Context, groupid string, affiliate *RadiusAffiliate, opts ...RequestOption) error {
+	return client.invoke(ctx, "PutRadiusAffiliate", groupid, func(ctx context.Context) error {
+		return client.putAffiliate(ctx, groupid, AffiliateRadius, affiliate.Status, "", opts)
+	})
+}
+
+// DeleteAffiliate removes the named affiliate from the group.
+func (client *Client) DeleteAffiliate(groupid string, name Affiliate, opts ...RequestOption) error {
+	return client.DeleteAffiliateContext(context.Background(), groupid, name, opts...)
+}
+
+// DeleteAffiliateContext is like DeleteAffiliate but carries ctx through to the API request.
+func (client *Client) DeleteAffiliateContext(ctx context.Context, groupid string, name Affiliate, opts ...RequestOption) error {
+	return client.invoke(ctx, "DeleteAffiliate", groupid, func(ctx context.Context) error {
+		resp, err := client.writeRequest(ctx, opts).
+			SetPathParam("groupid", groupid).
+			SetPathParam("affiliate", string(name)).
+			Delete("/group/{groupid}/affiliate/{affiliate}")
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return newAPIError(resp)
+		}
+		return nil
+	})
+}
+
+// getAffiliate fetches the named affiliate of the group.
+func (client *Client) getAffiliate(ctx context.Context, groupid string, name Affiliate) (*affiliateData, error) {
+	resp, err := client.request(ctx).
+		SetResult(affiliateResponse{}).
+		SetPathParam("groupid", groupid).
+		SetPathParam("affiliate", string(name)).
+		Get("/group/{groupid}/affiliate/{affiliate}")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, newAPIError(resp)
+	}
+	return &resp.Result().(*affiliateResponse).Data, nil
+}
+
+// putAffiliate sets the status and sender of the named affiliate of the group.
+func (client *Client) putAffiliate(ctx context.Context, groupid string, name Affiliate, status AffiliateStatus, sender string, opts []RequestOption) error {
+	if status != AffiliateStatusActive && status != AffiliateStatusInactive {
+		return fmt.Errorf("invalid affiliate status %q: must be active or inactive", status)
+	}
+
+	req := client.writeRequest(ctx, opts).
+		SetQueryParam("status", string(status))
+	if sender != "" {
+		req.SetQueryParam("sender", sender)
+	}
+
+	resp, err := req.
+		SetPathParam("groupid", groupid).
+		SetPathParam("affiliate", string(name)).
+		Put("/group/{groupid}/affiliate/{affiliate}")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return newAPIError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package gws_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+func TestAffiliateRoundTrip(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_test"})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email := &gws.EmailAffiliate{Status: gws.AffiliateStatusActive, Senders: "joeuser,janeuser"}
+	if err := client.PutEmailAffiliate("u_test", email); err != nil {
+		t.Fatalf("PutEmailAffiliate: %v", err)
+	}
+	if got, err := client.GetEmailAffiliate("u_test"); err != nil || *got != *email {
+		t.Errorf("GetEmailAffiliate = %+v, %v; want %+v", got, err, email)
+	}
+	reqs := srv.Requests()
+	put := reqs[0]
+	q, _ := url.ParseQuery(put.Query)
+	if put.Path != "/group/u_test/affiliate/email" || q.Get("status") != "active" || q.Get("sender") != "joeuser,janeuser" {
+		t.Errorf("PutEmailAffiliate sent %s %s?%s", put.Method, put.Path, put.Query)
+	}
+
+	google := &gws.GoogleAffiliate{Status: gws.AffiliateStatusInactive, Sender: gws.GoogleSenderMembers}
+	if err := client.PutGoogleAffiliate("u_test", google); err != nil {
+		t.Fatalf("PutGoogleAffiliate: %v", err)
+	}
+	if got, err := client.GetGoogleAffiliate("u_test"); err != nil || *got != *google {
+		t.Errorf("GetGoogleAffiliate = %+v, %v; want %+v", got, err, google)
+	}
+
+	radius := &gws.RadiusAffiliate{Status: gws.AffiliateStatusActive}
+	if err := client.PutRadiusAffiliate("u_test", radius); err != nil {
+		t.Fatalf("PutRadiusAffiliate: %v", err)
+	}
+	if got, err := client.GetRadiusAffiliate("u_test"); err != nil || *got != *radius {
+		t.Errorf("GetRadiusAffiliate = %+v, %v; want %+v", got, err, radius)
+	}
+	if q, _ := url.ParseQuery(srv.Requests()[len(reqs)+2].Query); q.Has("sender") {
+		t.Errorf("PutRadiusAffiliate sent sender %q", q.Get("sender"))
+	}
+
+	if err := client.DeleteAffiliate("u_test", gws.AffiliateGoogle); err != nil {
+		t.Fatalf("DeleteAffiliate: %v", err)
+	}
+	if _, err := client.GetGoogleAffiliate("u_test"); !errors.Is(err, gws.ErrNotFound) {
+		t.Errorf("GetGoogleAffiliate after delete = %v, want ErrNotFound", err)
+	}
+	if err := client.DeleteAffiliate("u_test", gws.AffiliateGoogle); !errors.Is(err, gws.ErrNotFound) {
+		t.Errorf("second DeleteAffiliate = %v, want ErrNotFound", err)
+	}
+	if _, err := client.GetEmailAffiliate("u_test"); err != nil {
+		t.Errorf("GetEmailAffiliate after deleting google: %v", err)
+	}
+}
+
+func TestAffiliateValidation(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_test"})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.PutRadiusAffiliate("u_test", &gws.RadiusAffiliate{Status: "enabled"}); err == nil {
+		t.Error("PutRadiusAffiliate with status enabled succeeded")
+	}
+	if err := client.PutGoogleAffiliate("u_test", &gws.GoogleAffiliate{Status: gws.AffiliateStatusActive, Sender: "anyone"}); err == nil {
+		t.Error("PutGoogleAffiliate with sender anyone succeeded")
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("sent %d requests for invalid affiliates, want 0", n)
+	}
+	if err := client.PutRadiusAffiliate("u_missing", &gws.RadiusAffiliate{Status: gws.AffiliateStatusActive}); !errors.Is(err, gws.ErrNotFound) {
+		t.Errorf("PutRadiusAffiliate of a missing group = %v, want ErrNotFound", err)
+	}
+}
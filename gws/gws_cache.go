@@ -0,0 +1,338 @@
+package gws
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CacheConfig enables an in-memory read-through cache of groups and memberships on the
+// client. Writes made through the same client drop the entries they affect. Changes made
+// elsewhere show up once an entry expires, or sooner with Client.InvalidateCache.
+//
+// Without synchronized writes, a read soon after a write may see, and cache, the state
+// from before it. Use WithSynchronized or Config.Synchronized to avoid that.
+type CacheConfig struct {
+	// GroupTTL is how long a group is served from the cache. After that it is revalidated
+	// with If-None-Match, which costs a request but no transfer when unchanged. Zero does
+	// not cache groups.
+	GroupTTL time.Duration
+
+	// MembershipTTL is how long direct and effective memberships and single member lookups,
+	// such as IsMember, are served from the cache. Zero does not cache them.
+	MembershipTTL time.Duration
+
+	// NegativeTTL is how long a 404 for a group or member is remembered, zero disables
+	// negative caching. 404s are only remembered for reads whose TTL above is not zero.
+	NegativeTTL time.Duration
+
+	// MaxBytes bounds the approximate memory held by cached entries. The least recently
+	// used entries are evicted beyond it. Zero means 64 MiB.
+	MaxBytes int64
+}
+
+// CacheStats counts cache activity since the client was created.
+type CacheStats struct {
+	// Hits are reads answered from the cache, including cached 404s
+	Hits uint64
+
+	// Misses are reads sent to the API because nothing usable was cached
+	Misses uint64
+
+	// Revalidations are expired groups confirmed unchanged with a 304 Not Modified
+	Revalidations uint64
+
+	// Evictions are entries dropped to stay within MaxBytes
+	Evictions uint64
+
+	// Entries and Bytes describe what is cached now
+	Entries int
+	Bytes   int64
+}
+
+// DefaultCacheConfig returns a cache configuration suited to web applications.
+func DefaultCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		GroupTTL:      5 * time.Minute,
+		MembershipTTL: time.Minute,
+		NegativeTTL:   30 * time.Second,
+		MaxBytes:      64 << 20,
+	}
+}
+
+// cacheKind says what a cache entry holds.
+type cacheKind uint8
+
+const (
+	cacheGroup cacheKind = iota
+	cacheMembers
+	cacheEffectiveMembers
+	cacheMember
+	cacheEffectiveMember
+)
+
+// effective reports whether entries of this kind depend on nested groups.
+func (k cacheKind) effective() bool {
+	return k == cacheEffectiveMembers || k == cacheEffectiveMember
+}
+
+// cacheKey identifies a cache entry. member is only set for single member lookups.
+type cacheKey struct {
+	kind   cacheKind
+	group  string
+	member string
+}
+
+// cacheEntry is a cached value, or a cached 404 when err is set.
+type cacheEntry struct {
+	key     cacheKey
+	value   any // *Group, MemberList or *Member
+	err     error
+	size    int64
+	expires time.Time
+	effGen  uint64
+}
+
+// cache is an LRU of API results bounded by approximate size. Group entries that have
+// expired are kept so they can be revalidated.
+type cache struct {
+	cfg CacheConfig
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recent first
+	items   map[cacheKey]*list.Element
+	byGroup map[string]map[cacheKey]struct{}
+	aliases map[string]string // group ID to regid and back, from cached groups
+	bytes   int64
+	stats   CacheStats
+
+	// writeGen counts invalidations, so results fetched across one are not cached.
+	// effGen counts membership writes, each of which makes every effective entry stale.
+	writeGen uint64
+	effGen   uint64
+}
+
+// newCache returns a cache for cfg, nil if cfg is nil.
+func newCache(cfg *CacheConfig) *cache {
+	if cfg == nil {
+		return nil
+	}
+	c := &cache{
+		cfg:     *cfg,
+		lru:     list.New(),
+		items:   make(map[cacheKey]*list.Element),
+		byGroup: make(map[string]map[cacheKey]struct{}),
+		aliases: make(map[string]string),
+	}
+	if c.cfg.MaxBytes <= 0 {
+		c.cfg.MaxBytes = 64 << 20
+	}
+	return c
+}
+
+// ttl returns how long a result of kind is cached, zero if it is not.
+func (c *cache) ttl(kind cacheKind, err error) time.Duration {
+	ttl := c.cfg.MembershipTTL
+	if kind == cacheGroup {
+		ttl = c.cfg.GroupTTL
+	}
+	if err != nil {
+		// A kind that is not cached is not cached as missing either
+		if ttl <= 0 || !errors.Is(err, ErrNotFound) {
+			return 0
+		}
+		return c.cfg.NegativeTTL
+	}
+	return ttl
+}
+
+// get looks up key. It returns the entry, if any, and whether it is fresh. A stale group
+// entry is returned for revalidation; other stale entries are not returned. The caller
+// must not change the entry's value. get also returns the generation to pass to put.
+func (c *cache) get(key cacheKey) (e *cacheEntry, fresh bool, gen uint64) {
+	if c == nil {
+		return nil, false, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gen = c.writeGen
+	if el, ok := c.items[key]; ok {
+		e = el.Value.(*cacheEntry)
+		if key.kind.effective() && e.effGen != c.effGen {
+			c.remove(el)
+			e = nil
+		} else if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return e, true, gen
+		} else if key.kind != cacheGroup || e.err != nil {
+			e = nil
+		}
+	}
+	c.stats.Misses++
+	return e, false, gen
+}
+
+// put caches the result of a read that started at generation gen, unless an
+// invalidation has happened since or the result is not cacheable.
+func (c *cache) put(key cacheKey, gen uint64, value any, err error) {
+	if c == nil {
+		return
+	}
+	ttl := c.ttl(key.kind, err)
+	if ttl <= 0 {
+		return
+	}
+	size := entrySize(key, value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.writeGen || size > c.cfg.MaxBytes {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	e := &cacheEntry{key: key, value: value, err: err, size: size, expires: time.Now().Add(ttl), effGen: c.effGen}
+	c.items[key] = c.lru.PushFront(e)
+	if c.byGroup[key.group] == nil {
+		c.byGroup[key.group] = make(map[cacheKey]struct{})
+	}
+	c.byGroup[key.group][key] = struct{}{}
+	if g, ok := value.(*Group); ok && g.ID != "" && g.Regid != "" {
+		c.aliases[g.ID], c.aliases[g.Regid] = g.Regid, g.ID
+	}
+	c.bytes += size
+	for c.bytes > c.cfg.MaxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// revalidated extends a group entry after the API answered 304 Not Modified.
+func (c *cache) revalidated(key cacheKey, gen uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Revalidations++
+	if el, ok := c.items[key]; ok && gen == c.writeGen {
+		el.Value.(*cacheEntry).expires = time.Now().Add(c.cfg.GroupTTL)
+		c.lru.MoveToFront(el)
+	}
+}
+
+// invalidate drops every entry of the given groups, by ID or regid. When membership is
+// true every effective entry goes too, since nesting can carry a membership change into
+// any other group. With no groups the whole cache is cleared.
+func (c *cache) invalidate(membership bool, groupids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeGen++
+	if membership {
+		c.effGen++
+	}
+	if len(groupids) == 0 {
+		c.lru.Init()
+		clear(c.items)
+		clear(c.byGroup)
+		clear(c.aliases)
+		c.bytes = 0
+		return
+	}
+	for _, id := range groupids {
+		for _, g := range []string{id, c.aliases[id]} {
+			if g == "" {
+				continue
+			}
+			for key := range c.byGroup[g] {
+				c.remove(c.items[key])
+			}
+		}
+	}
+}
+
+// remove drops an entry. The caller holds c.mu.
+func (c *cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.items, e.key)
+	if keys := c.byGroup[e.key.group]; keys != nil {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.byGroup, e.key.group)
+		}
+	}
+	if g, ok := e.value.(*Group); ok {
+		delete(c.aliases, g.ID)
+		delete(c.aliases, g.Regid)
+	}
+	c.bytes -= e.size
+}
+
+// entrySize estimates the memory held by a cache entry.
+func entrySize(key cacheKey, value any) int64 {
+	const overhead = 128 // entry, list element and map slot
+	size := int64(overhead + len(key.group) + len(key.member))
+	switch v := value.(type) {
+	case *Group:
+		size += 512 + int64(len(v.ID)+len(v.Regid)+len(v.DisplayName)+len(v.Description)+len(v.DependsOn)+len(v.etag))
+		for _, l := range []EntityList{v.Admins, v.Updaters, v.Creators, v.Readers, v.Optins, v.Optouts} {
+			for _, e := range l {
+				size += 64 + int64(len(e.Type)+len(e.ID)+len(e.Name))
+			}
+		}
+	case MemberList:
+		for _, m := range v {
+			size += 64 + int64(len(m.Type)+len(m.ID)+len(m.MType)+len(m.Source))
+		}
+	case *Member:
+		size += 64 + int64(len(v.Type)+len(v.ID))
+	}
+	return size
+}
+
+// cachedMembers returns a copy of a cached membership, or the cached error.
+func cachedMembers(ctx context.Context, e *cacheEntry) (*MemberList, error) {
+	if e.err != nil {
+		return &MemberList{}, e.err
+	}
+	members := slices.Clone(e.value.(MemberList))
+	setMemberCount(ctx, len(members))
+	return &members, nil
+}
+
+// cachedMember returns a copy of a cached member, or the cached error.
+func cachedMember(e *cacheEntry) (*Member, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	m := *e.value.(*Member)
+	return &m, nil
+}
+
+// CacheStats returns the cache counters, zero when Config.Cache is nil.
+func (client *Client) CacheStats() CacheStats {
+	if client.cache == nil {
+		return CacheStats{}
+	}
+	client.cache.mu.Lock()
+	defer client.cache.mu.Unlock()
+	stats := client.cache.stats
+	stats.Entries = len(client.cache.items)
+	stats.Bytes = client.cache.bytes
+	return stats
+}
+
+// InvalidateCache drops cached entries for the given groups, and every cached effective
+// membership, for changes made other than through this client. With no groups the whole
+// cache is cleared.
+func (client *Client) InvalidateCache(groupids ...string) {
+	client.cache.invalidate(true, groupids...)
+}
@@ -0,0 +1,285 @@
+package gws_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// newCacheClient returns a server with u_test holding joeuser and a client of it caching with cfg.
+func newCacheClient(t *testing.T, cfg *gws.CacheConfig) (*gwstest.Server, *gws.Client) {
+	t.Helper()
+	srv := gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPeople("joeuser", "janeuser")
+	srv.AddGroup(gws.Group{ID: "u_test", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "joeuser"}}},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"})
+	config := srv.Config()
+	config.Cache = cfg
+	client, err := gws.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	srv, client := newCacheClient(t, gws.DefaultCacheConfig())
+
+	for range 3 {
+		if _, err := client.GetGroup("u_test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetMembership("u_test"); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := client.IsMember("u_test", "joeuser"); err != nil || !ok {
+			t.Fatalf("IsMember = %v, %v", ok, err)
+		}
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("sent %d requests, want one per kind of read", n)
+	}
+	if stats := client.CacheStats(); stats.Hits != 6 || stats.Misses != 3 || stats.Entries != 3 {
+		t.Errorf("stats = %+v, want 6 hits, 3 misses and 3 entries", stats)
+	}
+
+	// Cached values are copies
+	group, _ := client.GetGroup("u_test")
+	group.DisplayName = "changed"
+	if again, _ := client.GetGroup("u_test"); again.DisplayName == "changed" {
+		t.Error("changing a returned group changed the cached one")
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(*gws.Client, *gws.Group) error
+	}{
+		{"UpdateGroup", func(c *gws.Client, g *gws.Group) error {
+			g.DisplayName = "Renamed"
+			_, err := c.UpdateGroup(g)
+			return err
+		}},
+		{"AddMembers", func(c *gws.Client, _ *gws.Group) error {
+			_, err := c.AddMembers("u_test", "janeuser")
+			return err
+		}},
+		{"AddMembers by regid", func(c *gws.Client, g *gws.Group) error {
+			_, err := c.AddMembers(g.Regid, "janeuser")
+			return err
+		}},
+		{"DeleteMembers", func(c *gws.Client, _ *gws.Group) error {
+			return c.DeleteMembers("u_test", "joeuser")
+		}},
+		{"InvalidateCache by regid", func(c *gws.Client, g *gws.Group) error {
+			c.InvalidateCache(g.Regid)
+			return nil
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, client := newCacheClient(t, gws.DefaultCacheConfig())
+			group, err := client.GetGroup("u_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.GetMembership("u_test"); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.write(client, group); err != nil {
+				t.Fatal(err)
+			}
+
+			gets := countRequests(srv, http.MethodGet)
+			fresh, err := client.GetGroup("u_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			members, err := client.GetMembership("u_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := countRequests(srv, http.MethodGet) - gets; n != 2 {
+				t.Errorf("sent %d GETs after the write, want the group and membership read again", n)
+			}
+			want, _ := srv.Group("u_test")
+			if fresh.DisplayName != want.DisplayName || !slices.Equal(members.ToIDs(), srv.Members("u_test").ToIDs()) {
+				t.Errorf("read %q with %v, want %q with %v", fresh.DisplayName, members.ToIDs(), want.DisplayName, srv.Members("u_test").ToIDs())
+			}
+		})
+	}
+}
+
+func TestCacheRegidAlias(t *testing.T) {
+	srv, client := newCacheClient(t, gws.DefaultCacheConfig())
+	group, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetGroup(group.Regid); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Fatalf("sent %d requests, want the ID and the regid read once each", n)
+	}
+
+	// A write by ID drops the entry cached under the regid as well
+	if _, err := client.AddMembers("u_test", "janeuser"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetGroup(group.Regid); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRequests(srv, http.MethodGet); n != 3 {
+		t.Errorf("sent %d GETs, want the regid read again after the write", n)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  gws.CacheConfig
+		gets int
+	}{
+		{"cached", gws.CacheConfig{GroupTTL: time.Minute, MembershipTTL: time.Minute, NegativeTTL: time.Minute}, 2},
+		{"no NegativeTTL", gws.CacheConfig{GroupTTL: time.Minute, MembershipTTL: time.Minute}, 4},
+		{"no GroupTTL", gws.CacheConfig{MembershipTTL: time.Minute, NegativeTTL: time.Minute}, 3},
+		{"no MembershipTTL", gws.CacheConfig{GroupTTL: time.Minute, NegativeTTL: time.Minute}, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, client := newCacheClient(t, &tc.cfg)
+			for range 2 {
+				if _, err := client.GetGroup("u_missing"); !errors.Is(err, gws.ErrNotFound) {
+					t.Fatalf("GetGroup(u_missing) = %v, want ErrNotFound", err)
+				}
+				if _, err := client.GetMember("u_test", "nobody"); !errors.Is(err, gws.ErrNotFound) {
+					t.Fatalf("GetMember(nobody) = %v, want ErrNotFound", err)
+				}
+			}
+			if n := len(srv.Requests()); n != tc.gets {
+				t.Errorf("sent %d requests, want %d", n, tc.gets)
+			}
+		})
+	}
+}
+
+func TestCacheNegativeInvalidated(t *testing.T) {
+	srv, client := newCacheClient(t, gws.DefaultCacheConfig())
+	if ok, err := client.IsMember("u_test", "janeuser"); err != nil || ok {
+		t.Fatalf("IsMember(janeuser) = %v, %v; want false", ok, err)
+	}
+	if _, err := client.AddMembers("u_test", "janeuser"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := client.IsMember("u_test", "janeuser"); err != nil || !ok {
+		t.Errorf("IsMember(janeuser) after adding = %v, %v; want true", ok, err)
+	}
+	if n := countRequests(srv, http.MethodGet); n != 2 {
+		t.Errorf("sent %d GETs, want the cached 404 dropped by the add", n)
+	}
+}
+
+func TestCacheETagRevalidation(t *testing.T) {
+	// Groups expire at once and are revalidated on every read
+	srv, client := newCacheClient(t, &gws.CacheConfig{GroupTTL: time.Nanosecond})
+	first, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Regid != first.Regid {
+		t.Errorf("revalidated group = %+v, want %+v", second, first)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || reqs[1].Header.Get("If-None-Match") == "" {
+		t.Fatalf("second read sent %d requests, want one with If-None-Match", len(reqs)-1)
+	}
+	if stats := client.CacheStats(); stats.Revalidations != 1 {
+		t.Errorf("Revalidations = %d, want 1", stats.Revalidations)
+	}
+
+	// A change behind the client's back is picked up by the next revalidation
+	renamed := first.Clone()
+	renamed.DisplayName = "Renamed elsewhere"
+	srv.AddGroup(*renamed)
+	if got, err := client.GetGroup("u_test"); err != nil || got.DisplayName != "Renamed elsewhere" {
+		t.Errorf("GetGroup after an outside change = %+v, %v", got, err)
+	}
+}
+
+func TestCacheGetGroupIfChangedRevalidates(t *testing.T) {
+	srv, client := newCacheClient(t, &gws.CacheConfig{GroupTTL: 50 * time.Millisecond})
+	group, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	if g, changed, err := client.GetGroupIfChanged("u_test", group.ETag()); err != nil || changed || g != nil {
+		t.Fatalf("GetGroupIfChanged = %v, %v, %v; want unchanged", g, changed, err)
+	}
+	// The 304 refreshed the expired entry, so this read is a hit
+	if _, err := client.GetGroup("u_test"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("sent %d requests, want the GetGroup and the GetGroupIfChanged", n)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	for i := range 10 {
+		srv.AddGroup(gws.Group{ID: fmt.Sprintf("u_test%d", i)})
+	}
+	config := srv.Config()
+	config.Cache = &gws.CacheConfig{GroupTTL: time.Minute}
+	probe, err := gws.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := probe.GetGroup("u_test0"); err != nil {
+		t.Fatal(err)
+	}
+	entry := probe.CacheStats().Bytes
+
+	// Room for three groups
+	config.Cache = &gws.CacheConfig{GroupTTL: time.Minute, MaxBytes: 3*entry + entry/2}
+	client, err := gws.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"u_test1", "u_test2", "u_test3", "u_test1", "u_test4"} {
+		if _, err := client.GetGroup(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := client.CacheStats()
+	if stats.Entries != 3 || stats.Evictions != 1 || stats.Bytes > 3*entry+entry/2 {
+		t.Fatalf("stats = %+v, want 3 entries within %d bytes after 1 eviction", stats, 3*entry+entry/2)
+	}
+
+	// u_test2 was the least recently used, u_test1 was read again before u_test4 came in
+	gets := len(srv.Requests())
+	for _, id := range []string{"u_test1", "u_test3", "u_test4"} {
+		client.GetGroup(id)
+	}
+	if n := len(srv.Requests()) - gets; n != 0 {
+		t.Errorf("sent %d requests for groups that should still be cached", n)
+	}
+	client.GetGroup("u_test2")
+	if n := len(srv.Requests()) - gets; n != 1 {
+		t.Errorf("sent %d requests for the evicted group, want 1", n)
+	}
+}
@@ -0,0 +1,303 @@
+package gws
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// defaultCertExpiryWarning is how close to expiry a client certificate gets before a warning is logged.
+const defaultCertExpiryWarning = 14 * 24 * time.Hour
+
+// CertificateSource supplies the client certificate presented to the API. The client asks
+// for the certificate at every new TLS connection, so a source can change it at any time.
+type CertificateSource interface {
+	// Certificate returns the certificate to present.
+	Certificate() (*tls.Certificate, error)
+
+	// Reload loads the certificate afresh, returning any error in doing so.
+	Reload() error
+}
+
+// FileCertificateSource loads a certificate and key from PEM files, or from a PKCS#12 file,
+// and reloads them when a file changes on disk or when ReloadInterval has passed since the
+// last load. Changes are noticed when the next TLS connection is made.
+type FileCertificateSource struct {
+	CertFile string
+	KeyFile  string
+
+	// ReloadInterval reloads the files this often even if they look unchanged, zero only
+	// reloads on change
+	ReloadInterval time.Duration
+
+	// decode reads the files, tls.LoadX509KeyPair when nil
+	decode func() (tls.Certificate, error)
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certStat fileStamp
+	keyStat  fileStamp
+	loadedAt time.Time
+}
+
+// fileStamp identifies a version of a file by its modification time and size.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewFileCertificateSource returns a source reading certFile and keyFile.
+func NewFileCertificateSource(certFile, keyFile string, reloadInterval time.Duration) *FileCertificateSource {
+	return &FileCertificateSource{CertFile: certFile, KeyFile: keyFile, ReloadInterval: reloadInterval}
+}
+
+// NewPKCS12FileCertificateSource returns a source reading the PKCS#12 (.p12 or .pfx) file
+// and decrypting it with password.
+func NewPKCS12FileCertificateSource(file, password string, reloadInterval time.Duration) *FileCertificateSource {
+	s := &FileCertificateSource{CertFile: file, ReloadInterval: reloadInterval}
+	s.decode = func() (tls.Certificate, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		return ParsePKCS12(data, password)
+	}
+	return s
+}
+
+// Certificate returns the loaded certificate, reloading it first if the files changed or
+// the reload interval passed. If that reload fails, for example because only one of the two
+// files has been replaced so far, the previous certificate is returned and the reload is
+// tried again next time.
+func (s *FileCertificateSource) Certificate() (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cert == nil || s.stale() {
+		if err := s.load(); err != nil && s.cert == nil {
+			return nil, err
+		}
+	}
+	return s.cert, nil
+}
+
+// Reload loads the certificate and key from disk now.
+func (s *FileCertificateSource) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// stale reports whether the files should be loaded again.
+func (s *FileCertificateSource) stale() bool {
+	if s.ReloadInterval > 0 && time.Since(s.loadedAt) >= s.ReloadInterval {
+		return true
+	}
+	return stampFile(s.CertFile) != s.certStat || stampFile(s.KeyFile) != s.keyStat
+}
+
+// load reads the files, keeping the current certificate if that fails.
+func (s *FileCertificateSource) load() error {
+	certStat, keyStat := stampFile(s.CertFile), stampFile(s.KeyFile)
+	decode := s.decode
+	if decode == nil {
+		decode = func() (tls.Certificate, error) { return tls.LoadX509KeyPair(s.CertFile, s.KeyFile) }
+	}
+	cert, err := decode()
+	if err != nil {
+		return err
+	}
+	s.cert = &cert
+	s.certStat, s.keyStat = certStat, keyStat
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// staticCertificateSource always presents the same certificate.
+type staticCertificateSource struct {
+	cert *tls.Certificate
+}
+
+// NewStaticCertificateSource returns a source that always presents cert.
+func NewStaticCertificateSource(cert tls.Certificate) CertificateSource {
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		// Filled in so expiry can be checked
+		cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+	}
+	return &staticCertificateSource{cert: &cert}
+}
+
+func (s *staticCertificateSource) Certificate() (*tls.Certificate, error) { return s.cert, nil }
+
+func (s *staticCertificateSource) Reload() error { return nil }
+
+// ParsePKCS12 decodes a PKCS#12 (.p12 or .pfx) bundle into a client certificate. Any
+// further certificates in the bundle are sent along as the certificate chain.
+func ParsePKCS12(data []byte, password string) (tls.Certificate, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert := tls.Certificate{PrivateKey: key, Leaf: leaf, Certificate: [][]byte{leaf.Raw}}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert, nil
+}
+
+// hasTLSSettings reports whether any CA or client certificate setting is present.
+func (c *Config) hasTLSSettings() bool {
+	return c.CAFile != "" || len(c.CAPEM) > 0 || c.RootCAs != nil ||
+		c.ClientCert != "" || c.ClientKey != "" || len(c.ClientCertPEM) > 0 || len(c.ClientKeyPEM) > 0 ||
+		c.PKCS12File != "" || c.Certificate != nil || c.CertificateSource != nil
+}
+
+// certificateSource returns the source of the configured client certificate, nil if none.
+func (c *Config) certificateSource() (CertificateSource, error) {
+	var sources []CertificateSource
+	if c.ClientCert != "" || c.ClientKey != "" {
+		sources = append(sources, NewFileCertificateSource(c.ClientCert, c.ClientKey, c.CertReloadInterval))
+	}
+	if len(c.ClientCertPEM) > 0 || len(c.ClientKeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(c.ClientCertPEM, c.ClientKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, NewStaticCertificateSource(cert))
+	}
+	if c.PKCS12File != "" {
+		sources = append(sources, NewPKCS12FileCertificateSource(c.PKCS12File, c.PKCS12Password, c.CertReloadInterval))
+	}
+	if c.Certificate != nil {
+		sources = append(sources, NewStaticCertificateSource(*c.Certificate))
+	}
+	if c.CertificateSource != nil {
+		sources = append(sources, c.CertificateSource)
+	}
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0], nil
+	}
+	return nil, errors.New("only one of ClientCert, ClientCertPEM, PKCS12File, Certificate and CertificateSource may be set")
+}
+
+// configureTLS sets up the CA certificates and the client certificate. configureTransport
+// has made sure there is an *http.Transport with a TLS config to hold them.
+func (client *Client) configureTLS() error {
+	cfg := client.config
+	if !cfg.hasTLSSettings() {
+		return nil
+	}
+	transport, err := client.resty.Transport()
+	if err != nil {
+		return err
+	}
+	tlsConfig := transport.TLSClientConfig
+
+	if cfg.RootCAs != nil {
+		tlsConfig.RootCAs = cfg.RootCAs.Clone()
+	}
+	if cfg.CAFile != "" {
+		client.resty.SetRootCertificate(cfg.CAFile)
+	}
+	if len(cfg.CAPEM) > 0 {
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(cfg.CAPEM) {
+			return errors.New("no certificates found in CAPEM")
+		}
+	}
+
+	source, err := cfg.certificateSource()
+	if err != nil || source == nil {
+		return err
+	}
+	// Load now so that a missing or broken keypair fails NewClient
+	if err := source.Reload(); err != nil {
+		return err
+	}
+	client.certSource = source
+	tlsConfig.GetClientCertificate = client.getClientCertificate
+	return nil
+}
+
+// stampFile returns the stamp of the named file, zero if it cannot be read.
+func stampFile(name string) fileStamp {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// ReloadCredentials reloads the client certificate from its source now and closes idle
+// connections, so that the next request presents the new certificate. Connections with
+// requests in flight finish with the certificate they were opened with.
+func (client *Client) ReloadCredentials() error {
+	client.configure()
+	if client.configErr != nil {
+		return client.configErr
+	}
+	if client.certSource == nil {
+		return errors.New("no client certificate configured")
+	}
+	if err := client.certSource.Reload(); err != nil {
+		return err
+	}
+	cert, err := client.certSource.Certificate()
+	if err != nil {
+		return err
+	}
+	client.checkCertExpiry(cert)
+	client.closeIdleConnections()
+	return nil
+}
+
+// getClientCertificate is the tls.Config hook presenting the certificate from the source.
+func (client *Client) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := client.certSource.Certificate()
+	if err != nil {
+		return nil, err
+	}
+	client.checkCertExpiry(cert)
+	return cert, nil
+}
+
+// checkCertExpiry logs a warning, once per certificate, when cert expires within
+// Config.CertExpiryWarning.
+func (client *Client) checkCertExpiry(cert *tls.Certificate) {
+	window := client.config.CertExpiryWarning
+	if window == 0 {
+		window = defaultCertExpiryWarning
+	}
+	if client.config.Logger == nil || window < 0 || cert.Leaf == nil {
+		return
+	}
+	if client.certChecked.Swap(cert) == cert {
+		return
+	}
+	if left := time.Until(cert.Leaf.NotAfter); left < window {
+		client.config.Logger.Warn("gws client certificate expires soon",
+			slog.String("subject", cert.Leaf.Subject.String()),
+			slog.Time("not_after", cert.Leaf.NotAfter),
+			slog.Duration("remaining", left.Round(time.Minute)))
+	}
+}
+
+// closeIdleConnections closes keep-alive connections so new requests open fresh ones.
+func (client *Client) closeIdleConnections() {
+	if client.transport != nil {
+		// The wrapping transports do not pass CloseIdleConnections on
+		client.transport.CloseIdleConnections()
+		return
+	}
+	client.resty.GetClient().CloseIdleConnections()
+}
@@ -0,0 +1,279 @@
+package gws_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testCert is a throwaway certificate with its key. Ed25519 keys and fixed validity keep
+// the PEM of certificates whose names have the same length the same size.
+type testCert struct {
+	cert *x509.Certificate
+	key  ed25519.PrivateKey
+}
+
+// newTestCert returns a certificate for cn signed by parent, self-signed when parent is nil.
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, pub, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// writeFile writes data to name and sets its modification time to mtime.
+func writeFile(t *testing.T, name string, data []byte, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// subject returns the common name of the certificate source presents.
+func subject(t *testing.T, source gws.CertificateSource) string {
+	t.Helper()
+	cert, err := source.Certificate()
+	if err != nil {
+		t.Fatalf("Certificate: %v", err)
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestFileCertificateSourceReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	a, b := newTestCert(t, "a", nil), newTestCert(t, "b", nil)
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, certFile, a.certPEM(), start)
+	writeFile(t, keyFile, a.keyPEM(t), start)
+
+	source := gws.NewFileCertificateSource(certFile, keyFile, 0)
+	if got := subject(t, source); got != "a" {
+		t.Fatalf("first certificate = %s, want a", got)
+	}
+
+	// Half way through a rotation the pair does not match, so the old one is kept
+	writeFile(t, certFile, b.certPEM(), start.Add(time.Minute))
+	if got := subject(t, source); got != "a" {
+		t.Errorf("certificate with only the cert file replaced = %s, want a", got)
+	}
+	if err := source.Reload(); err == nil {
+		t.Error("Reload of a mismatched pair succeeded")
+	}
+	writeFile(t, keyFile, b.keyPEM(t), start.Add(time.Minute))
+	if got := subject(t, source); got != "b" {
+		t.Errorf("certificate after rotation = %s, want b", got)
+	}
+
+	os.Remove(certFile)
+	if err := source.Reload(); err == nil {
+		t.Error("Reload of a missing file succeeded")
+	}
+	if got := subject(t, source); got != "b" {
+		t.Errorf("certificate after the file went missing = %s, want b", got)
+	}
+}
+
+func TestFileCertificateSourceReloadInterval(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	a, b := newTestCert(t, "a", nil), newTestCert(t, "b", nil)
+	mtime := time.Now().Add(-time.Hour)
+	writeFile(t, certFile, a.certPEM(), mtime)
+	writeFile(t, keyFile, a.keyPEM(t), mtime)
+
+	onChange := gws.NewFileCertificateSource(certFile, keyFile, 0)
+	always := gws.NewFileCertificateSource(certFile, keyFile, time.Nanosecond)
+	subject(t, onChange)
+	subject(t, always)
+
+	// Same size and modification time, so only the interval notices the change
+	writeFile(t, certFile, b.certPEM(), mtime)
+	writeFile(t, keyFile, b.keyPEM(t), mtime)
+	if got := subject(t, onChange); got != "a" {
+		t.Errorf("certificate without a reload interval = %s, want a", got)
+	}
+	if got := subject(t, always); got != "b" {
+		t.Errorf("certificate after the reload interval = %s, want b", got)
+	}
+}
+
+func TestParsePKCS12(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	leaf := newTestCert(t, "client", ca)
+	pfx, err := pkcs12.Modern.Encode(leaf.key, leaf.cert, []*x509.Certificate{ca.cert}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := gws.ParsePKCS12(pfx, "secret")
+	if err != nil {
+		t.Fatalf("ParsePKCS12: %v", err)
+	}
+	if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "client" {
+		t.Errorf("Leaf = %v, want the client certificate", cert.Leaf)
+	}
+	if len(cert.Certificate) != 2 || string(cert.Certificate[0]) != string(leaf.cert.Raw) || string(cert.Certificate[1]) != string(ca.cert.Raw) {
+		t.Errorf("chain has %d certificates, want the client then the CA", len(cert.Certificate))
+	}
+	if key, ok := cert.PrivateKey.(ed25519.PrivateKey); !ok || !key.Equal(leaf.key) {
+		t.Errorf("PrivateKey = %T, want the client key", cert.PrivateKey)
+	}
+
+	if _, err := gws.ParsePKCS12(pfx, "wrong"); err == nil {
+		t.Error("ParsePKCS12 with the wrong password succeeded")
+	}
+
+	file := filepath.Join(t.TempDir(), "client.p12")
+	writeFile(t, file, pfx, time.Now())
+	if got := subject(t, gws.NewPKCS12FileCertificateSource(file, "secret", 0)); got != "client" {
+		t.Errorf("PKCS#12 file certificate = %s, want client", got)
+	}
+	if err := gws.NewPKCS12FileCertificateSource(file, "wrong", 0).Reload(); err == nil {
+		t.Error("PKCS#12 file source with the wrong password loaded")
+	}
+}
+
+func TestClientCertificateConfig(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCert(t, "client", nil)
+	certFile, keyFile, p12File := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), filepath.Join(dir, "client.p12")
+	writeFile(t, certFile, c.certPEM(), time.Now())
+	writeFile(t, keyFile, c.keyPEM(t), time.Now())
+	pfx, err := pkcs12.Modern.Encode(c.key, c.cert, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, p12File, pfx, time.Now())
+	ready, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		cfg     gws.Config
+		wantErr string
+	}{
+		{"PEM files", gws.Config{ClientCert: certFile, ClientKey: keyFile}, ""},
+		{"PEM data", gws.Config{ClientCertPEM: c.certPEM(), ClientKeyPEM: c.keyPEM(t)}, ""},
+		{"PKCS#12", gws.Config{PKCS12File: p12File, PKCS12Password: "secret"}, ""},
+		{"Certificate", gws.Config{Certificate: &ready}, ""},
+		{"missing key file", gws.Config{ClientCert: certFile, ClientKey: filepath.Join(dir, "missing.key")}, "no such file"},
+		{"bad PEM data", gws.Config{ClientCertPEM: []byte("not a certificate"), ClientKeyPEM: c.keyPEM(t)}, "certificate"},
+		{"PKCS#12 wrong password", gws.Config{PKCS12File: p12File, PKCS12Password: "wrong"}, "password"},
+		{"cert file and PKCS#12", gws.Config{ClientCert: certFile, ClientKey: keyFile, PKCS12File: p12File}, "only one of"},
+		{"PEM data and Certificate", gws.Config{ClientCertPEM: c.certPEM(), ClientKeyPEM: c.keyPEM(t), Certificate: &ready}, "only one of"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.APIUrl = "https://gws.test"
+			_, err := gws.NewClient(&tc.cfg)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("NewClient: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("NewClient error = %v, want one mentioning %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReloadCredentialsPresentsNewCertificate(t *testing.T) {
+	var mu sync.Mutex
+	var presented []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		presented = append(presented, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"status":404,"detail":["Group not found"]}]}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	a, b := newTestCert(t, "a", nil), newTestCert(t, "b", nil)
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, certFile, a.certPEM(), start)
+	writeFile(t, keyFile, a.keyPEM(t), start)
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	client, err := gws.NewClient(&gws.Config{APIUrl: srv.URL, RootCAs: roots, ClientCert: certFile, ClientKey: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.GetGroup("u_test")
+	writeFile(t, certFile, b.certPEM(), start.Add(time.Minute))
+	writeFile(t, keyFile, b.keyPEM(t), start.Add(time.Minute))
+	// The open connection keeps the certificate it was made with
+	client.GetGroup("u_test")
+	if err := client.ReloadCredentials(); err != nil {
+		t.Fatalf("ReloadCredentials: %v", err)
+	}
+	client.GetGroup("u_test")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"a", "a", "b"}; strings.Join(presented, ",") != strings.Join(want, ",") {
+		t.Errorf("presented %v, want %v", presented, want)
+	}
+}
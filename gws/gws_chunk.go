@@ -0,0 +1,113 @@
+package gws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxMemberPathLength is the most bytes of comma joined, escaped member IDs put in one
+// request path. It keeps the request line well under common server and proxy limits.
+const maxMemberPathLength = 2000
+
+// MemberChunkError reports the failure of one request of an AddMembers or DeleteMembers
+// call that was split into several. Errors from all failed chunks are joined with
+// errors.Join, so errors.Is and errors.As still find the underlying *APIError.
+type MemberChunkError struct {
+	// MemberIDs are the IDs sent in the failed request
+	MemberIDs []string
+
+	// Err is the failure of that request
+	Err error
+}
+
+func (e *MemberChunkError) Error() string {
+	return fmt.Sprintf("member chunk of %d starting at %s: %v", len(e.MemberIDs), e.MemberIDs[0], e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *MemberChunkError) Unwrap() error { return e.Err }
+
+// chunkMemberIDs splits ids into runs whose escaped, comma joined length stays within max.
+// An ID longer than max on its own gets a chunk to itself.
+func chunkMemberIDs(ids []string, max int) [][]string {
+	var chunks [][]string
+	var cur []string
+	size := 0
+	for _, id := range ids {
+		n := len(url.PathEscape(id))
+		if len(cur) > 0 && size+1+n > max {
+			chunks = append(chunks, cur)
+			cur, size = nil, 0
+		}
+		if len(cur) > 0 {
+			size++ // comma
+		}
+		cur = append(cur, id)
+		size += n
+	}
+	if len(cur) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// escapeMemberIDs escapes each ID as a path segment and joins them with commas, the
+// separator the API expects between member IDs in a path.
+func escapeMemberIDs(ids []string) string {
+	escaped := make([]string, len(ids))
+	for i, id := range ids {
+		escaped[i] = url.PathEscape(id)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// forMemberChunks calls fn for each URL-safe chunk of ids, up to Config.MemberChunkConcurrency
+// at a time, and merges the returned not found IDs in chunk order. Every chunk is attempted;
+// failures are reported together as joined *MemberChunkErrors alongside the IDs merged so far.
+func (client *Client) forMemberChunks(ctx context.Context, ids []string, fn func(context.Context, []string) ([]string, error)) ([]string, error) {
+	chunks := chunkMemberIDs(ids, maxMemberPathLength)
+	switch len(chunks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return fn(ctx, chunks[0])
+	}
+
+	notFound := make([][]string, len(chunks))
+	errs := make([]error, len(chunks))
+	run := func(i int) {
+		var err error
+		notFound[i], err = fn(ctx, chunks[i])
+		if err != nil {
+			errs[i] = &MemberChunkError{MemberIDs: chunks[i], Err: err}
+		}
+	}
+
+	if workers := client.config.MemberChunkConcurrency; workers > 1 {
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for i := range chunks {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				run(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range chunks {
+			run(i)
+		}
+	}
+
+	var merged []string
+	for _, nf := range notFound {
+		merged = append(merged, nf...)
+	}
+	return merged, errors.Join(errs...)
+}
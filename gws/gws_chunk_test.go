@@ -0,0 +1,46 @@
+package gws
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChunkMemberIDs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ids  []string
+		max  int
+		want [][]string
+	}{
+		{"none", nil, 10, nil},
+		{"one chunk", []string{"ab", "cd", "ef"}, 8, [][]string{{"ab", "cd", "ef"}}},
+		{"split at comma", []string{"ab", "cd", "ef"}, 7, [][]string{{"ab", "cd"}, {"ef"}}},
+		{"one per chunk", []string{"ab", "cd", "ef"}, 2, [][]string{{"ab"}, {"cd"}, {"ef"}}},
+		{"oversized ID alone", []string{"ab", "toolongid", "cd"}, 5, [][]string{{"ab"}, {"toolongid"}, {"cd"}}},
+		{"escaped length counts", []string{"a b", "cd"}, 6, [][]string{{"a b"}, {"cd"}}},
+	} {
+		if got := chunkMemberIDs(tc.ids, tc.max); !slices.EqualFunc(got, tc.want, slices.Equal) {
+			t.Errorf("%s: chunkMemberIDs(%q, %d) = %q, want %q", tc.name, tc.ids, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestChunkMemberIDsKeepsOrderAndLimit(t *testing.T) {
+	var ids []string
+	for i := range 1000 {
+		ids = append(ids, strings.Repeat("x", i%40)+"@example.edu")
+	}
+	chunks := chunkMemberIDs(ids, maxMemberPathLength)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	if got := slices.Concat(chunks...); !slices.Equal(got, ids) {
+		t.Error("chunks do not join back to the IDs in order")
+	}
+	for i, chunk := range chunks {
+		if n := len(escapeMemberIDs(chunk)); n > maxMemberPathLength {
+			t.Errorf("chunk %d is %d bytes, want at most %d", i, n, maxMemberPathLength)
+		}
+	}
+}
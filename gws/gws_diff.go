@@ -0,0 +1,167 @@
+package gws
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GroupDiff lists the changes that turn one Group into another. Entities are compared by
+// type and ID, so a change of display name alone is not a change.
+type GroupDiff struct {
+	// Fields holds the scalar fields that differ, in Group field order
+	Fields []FieldChange `json:"fields,omitempty"`
+
+	// Lists holds the entity lists with additions or removals, in Group field order
+	Lists []EntityListChange `json:"lists,omitempty"`
+}
+
+// FieldChange is a changed scalar field of a Group, with its values rendered as strings.
+type FieldChange struct {
+	// Field is the Group field name, such as DisplayName
+	Field string `json:"field"`
+
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// EntityListChange is the entities added to and removed from one EntityList of a Group.
+type EntityListChange struct {
+	// List is the Group field name, such as Admins
+	List string `json:"list"`
+
+	Added   EntityList `json:"added,omitempty"`
+	Removed EntityList `json:"removed,omitempty"`
+}
+
+// groupField gets and sets one scalar field of a Group as a string.
+type groupField struct {
+	name string
+	get  func(*Group) string
+	set  func(*Group, string) error
+}
+
+// groupFields are the scalar fields compared by DiffGroups. Timestamps, Gid and Regid are
+// maintained by the service and left out.
+var groupFields = []groupField{
+	{"DisplayName", func(g *Group) string { return g.DisplayName }, func(g *Group, v string) error { g.DisplayName = v; return nil }},
+	{"Description", func(g *Group) string { return g.Description }, func(g *Group, v string) error { g.Description = v; return nil }},
+	{"Contact", func(g *Group) string { return string(g.Contact) }, func(g *Group, v string) error { g.Contact = UWNetID(v); return nil }},
+	{"AuthnFactor", func(g *Group) string { return strconv.Itoa(g.AuthnFactor) }, func(g *Group, v string) (err error) {
+		g.AuthnFactor, err = strconv.Atoi(v)
+		return err
+	}},
+	{"Classification", func(g *Group) string { return string(g.Classification) }, func(g *Group, v string) error {
+		g.Classification = DataClassification(v)
+		return nil
+	}},
+	{"DependsOn", func(g *Group) string { return g.DependsOn }, func(g *Group, v string) error { g.DependsOn = v; return nil }},
+}
+
+// namedEntityList is an EntityList field of a Group.
+type namedEntityList struct {
+	name string
+	list *EntityList
+}
+
+// entityLists returns the EntityList fields of the group, in Group field order.
+func (group *Group) entityLists() []namedEntityList {
+	return []namedEntityList{
+		{"Admins", &group.Admins},
+		{"Updaters", &group.Updaters},
+		{"Creators", &group.Creators},
+		{"Readers", &group.Readers},
+		{"Optins", &group.Optins},
+		{"Optouts", &group.Optouts},
+	}
+}
+
+// DiffGroups returns the changes that turn a into b. A nil group is treated as empty.
+func DiffGroups(a, b *Group) GroupDiff {
+	if a == nil {
+		a = &Group{}
+	}
+	if b == nil {
+		b = &Group{}
+	}
+
+	var d GroupDiff
+	for _, f := range groupFields {
+		if av, bv := f.get(a), f.get(b); av != bv {
+			d.Fields = append(d.Fields, FieldChange{Field: f.name, Old: av, New: bv})
+		}
+	}
+	bLists := b.entityLists()
+	for i, al := range a.entityLists() {
+		added := entitiesMissing(*bLists[i].list, *al.list)
+		removed := entitiesMissing(*al.list, *bLists[i].list)
+		if len(added) > 0 || len(removed) > 0 {
+			d.Lists = append(d.Lists, EntityListChange{List: al.name, Added: added, Removed: removed})
+		}
+	}
+	return d
+}
+
+// entitiesMissing returns the entities of from that are not in other, compared by type and ID.
+func entitiesMissing(from, other EntityList) EntityList {
+	missing := difference(from, other, compareOptions{})
+	if len(missing) == 0 {
+		return nil
+	}
+	return missing
+}
+
+// IsEmpty reports whether the diff has no changes.
+func (d GroupDiff) IsEmpty() bool {
+	return len(d.Fields) == 0 && len(d.Lists) == 0
+}
+
+// Apply makes the changes of the diff to g. Fields are set to their new values whatever
+// they hold now. Removed entities are taken out if present and added ones put in if absent,
+// so a diff can be applied to a fresh copy of a group that has changed since it was taken.
+func (d GroupDiff) Apply(g *Group) error {
+	for _, c := range d.Fields {
+		i := slices.IndexFunc(groupFields, func(f groupField) bool { return f.name == c.Field })
+		if i < 0 {
+			return fmt.Errorf("unknown group field %q", c.Field)
+		}
+		if err := groupFields[i].set(g, c.New); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", c.Field, err)
+		}
+	}
+
+	lists := g.entityLists()
+	for _, c := range d.Lists {
+		i := slices.IndexFunc(lists, func(l namedEntityList) bool { return l.name == c.List })
+		if i < 0 {
+			return fmt.Errorf("unknown group entity list %q", c.List)
+		}
+		list := lists[i].list
+		removed := keySet(c.Removed, compareOptions{})
+		*list = slices.DeleteFunc(*list, func(e Entity) bool {
+			_, ok := removed[e.setKey(compareOptions{})]
+			return ok
+		})
+		list.AddEntity(c.Added...)
+	}
+	return nil
+}
+
+// String renders the diff one change per line, for previews: "~" marks a changed field,
+// "+" an added entity and "-" a removed one. An empty diff renders as "".
+func (d GroupDiff) String() string {
+	var b strings.Builder
+	for _, c := range d.Fields {
+		fmt.Fprintf(&b, "~ %s: %q -> %q\n", c.Field, c.Old, c.New)
+	}
+	for _, c := range d.Lists {
+		for _, e := range c.Removed {
+			fmt.Fprintf(&b, "- %s: %s (%s)\n", c.List, e.ID, e.Type)
+		}
+		for _, e := range c.Added {
+			fmt.Fprintf(&b, "+ %s: %s (%s)\n", c.List, e.ID, e.Type)
+		}
+	}
+	return b.String()
+}
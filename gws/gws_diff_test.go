@@ -0,0 +1,132 @@
+package gws_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+)
+
+func TestDiffGroupsAndApply(t *testing.T) {
+	joe := gws.Entity{Type: gws.EntityTypeUWNetID, ID: "joeuser"}
+	jane := gws.Entity{Type: gws.EntityTypeUWNetID, ID: "janeuser"}
+	group := gws.Entity{Type: gws.EntityTypeGroup, ID: "u_admins"}
+	base := func() *gws.Group {
+		return &gws.Group{
+			ID:             "u_test",
+			DisplayName:    "Test",
+			Description:    "A test group",
+			Contact:        "joeuser",
+			AuthnFactor:    1,
+			Classification: gws.DataClassificationPublic,
+			Admins:         gws.EntityList{joe},
+			Readers:        gws.EntityList{group},
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		change func(*gws.Group)
+		want   gws.GroupDiff
+	}{
+		{"none", func(*gws.Group) {}, gws.GroupDiff{}},
+		{"DisplayName", func(g *gws.Group) { g.DisplayName = "Renamed" },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "DisplayName", Old: "Test", New: "Renamed"}}}},
+		{"Description", func(g *gws.Group) { g.Description = "" },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "Description", Old: "A test group", New: ""}}}},
+		{"Contact", func(g *gws.Group) { g.Contact = "janeuser" },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "Contact", Old: "joeuser", New: "janeuser"}}}},
+		{"AuthnFactor", func(g *gws.Group) { g.AuthnFactor = 2 },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "AuthnFactor", Old: "1", New: "2"}}}},
+		{"Classification", func(g *gws.Group) { g.Classification = gws.DataClassificationRestricted },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "Classification", Old: "u", New: "r"}}}},
+		{"DependsOn", func(g *gws.Group) { g.DependsOn = "uw_employee" },
+			gws.GroupDiff{Fields: []gws.FieldChange{{Field: "DependsOn", Old: "", New: "uw_employee"}}}},
+		{"service fields ignored", func(g *gws.Group) { g.Regid = "abc"; g.Gid = 42; g.Created = 1 }, gws.GroupDiff{}},
+		{"admin added", func(g *gws.Group) { g.Admins = append(g.Admins, jane) },
+			gws.GroupDiff{Lists: []gws.EntityListChange{{List: "Admins", Added: gws.EntityList{jane}}}}},
+		{"admin replaced", func(g *gws.Group) { g.Admins = gws.EntityList{jane} },
+			gws.GroupDiff{Lists: []gws.EntityListChange{{List: "Admins", Added: gws.EntityList{jane}, Removed: gws.EntityList{joe}}}}},
+		{"entity name ignored", func(g *gws.Group) { g.Admins = gws.EntityList{{Type: joe.Type, ID: joe.ID, Name: "Joe"}} }, gws.GroupDiff{}},
+		{"several lists", func(g *gws.Group) {
+			g.Readers = nil
+			g.Optouts = gws.EntityList{jane}
+			g.Updaters = gws.EntityList{group}
+		}, gws.GroupDiff{Lists: []gws.EntityListChange{
+			{List: "Updaters", Added: gws.EntityList{group}},
+			{List: "Readers", Removed: gws.EntityList{group}},
+			{List: "Optouts", Added: gws.EntityList{jane}},
+		}}},
+		{"fields and lists", func(g *gws.Group) { g.DisplayName = "Renamed"; g.Creators = gws.EntityList{joe} },
+			gws.GroupDiff{
+				Fields: []gws.FieldChange{{Field: "DisplayName", Old: "Test", New: "Renamed"}},
+				Lists:  []gws.EntityListChange{{List: "Creators", Added: gws.EntityList{joe}}},
+			}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := base(), base()
+			tc.change(b)
+
+			d := gws.DiffGroups(a, b)
+			if !reflect.DeepEqual(d, tc.want) {
+				t.Fatalf("DiffGroups = %+v, want %+v", d, tc.want)
+			}
+			if d.IsEmpty() != (len(tc.want.Fields) == 0 && len(tc.want.Lists) == 0) {
+				t.Errorf("IsEmpty = %v", d.IsEmpty())
+			}
+
+			if err := d.Apply(a); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if again := gws.DiffGroups(a, b); !again.IsEmpty() {
+				t.Errorf("after Apply the groups still differ by %+v", again)
+			}
+		})
+	}
+}
+
+func TestApplyToChangedGroup(t *testing.T) {
+	joe := gws.Entity{Type: gws.EntityTypeUWNetID, ID: "joeuser"}
+	jane := gws.Entity{Type: gws.EntityTypeUWNetID, ID: "janeuser"}
+	d := gws.GroupDiff{
+		Fields: []gws.FieldChange{{Field: "DisplayName", Old: "Test", New: "Renamed"}},
+		Lists:  []gws.EntityListChange{{List: "Admins", Added: gws.EntityList{jane}, Removed: gws.EntityList{joe}}},
+	}
+
+	// Someone else renamed the group and already added jane and removed joe
+	g := &gws.Group{DisplayName: "Other name", Admins: gws.EntityList{jane}}
+	if err := d.Apply(g); err != nil {
+		t.Fatal(err)
+	}
+	if g.DisplayName != "Renamed" || !reflect.DeepEqual(g.Admins, gws.EntityList{jane}) {
+		t.Errorf("applied to a changed group: %q with %v", g.DisplayName, g.Admins)
+	}
+
+	for _, bad := range []gws.GroupDiff{
+		{Fields: []gws.FieldChange{{Field: "Regid", New: "abc"}}},
+		{Fields: []gws.FieldChange{{Field: "AuthnFactor", New: "two"}}},
+		{Lists: []gws.EntityListChange{{List: "Members", Added: gws.EntityList{joe}}}},
+	} {
+		if err := bad.Apply(&gws.Group{}); err == nil {
+			t.Errorf("Apply(%+v) succeeded", bad)
+		}
+	}
+}
+
+func TestDiffGroupsNilAndString(t *testing.T) {
+	g := &gws.Group{DisplayName: "Test", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "joeuser"}}}
+
+	created := gws.DiffGroups(nil, g)
+	want := "~ DisplayName: \"\" -> \"Test\"\n+ Admins: joeuser (uwnetid)\n"
+	if got := created.String(); got != want {
+		t.Errorf("String of a new group = %q, want %q", got, want)
+	}
+	deleted := gws.DiffGroups(g, nil)
+	want = "~ DisplayName: \"Test\" -> \"\"\n- Admins: joeuser (uwnetid)\n"
+	if got := deleted.String(); got != want {
+		t.Errorf("String of a deleted group = %q, want %q", got, want)
+	}
+	if got := gws.DiffGroups(g, g).String(); got != "" {
+		t.Errorf("String of no changes = %q, want empty", got)
+	}
+}
@@ -48,18 +48,14 @@ func NewEntityList() *EntityList {
 // Most commonly this is not used and instead Entities are added ByID using other functions.
 func (el *EntityList) AddEntity(e ...Entity) (*EntityList, error) {
 	// Check if each entity already exists in the EntityList
+	existing := keySet(*el, compareOptions{})
 	for _, newEntity := range e {
-		exists := false
-		for _, existingEntity := range *el {
-			if existingEntity.Type == newEntity.Type && existingEntity.ID == newEntity.ID {
-				exists = true
-				break
-			}
-		}
-		if exists {
+		key := newEntity.setKey(compareOptions{})
+		if _, exists := existing[key]; exists {
 			continue // Entity already exists, skip to the next one
 		}
 		*el = append(*el, newEntity)
+		existing[key] = struct{}{}
 	}
 
 	return el, nil
@@ -69,8 +65,9 @@ func (el *EntityList) AddEntity(e ...Entity) (*EntityList, error) {
 // Infers the entity type automatically.
 func (el *EntityList) AppendEntityByID(id ...string) (*EntityList, error) {
 
+	have := el.Index()
 	for _, idStr := range id {
-		if el.Contains(idStr) {
+		if have.Contains(idStr) {
 			continue
 		}
 		eType := inferredEType(idStr)
@@ -78,6 +75,7 @@ func (el *EntityList) AppendEntityByID(id ...string) (*EntityList, error) {
 			return el, fmt.Errorf("Entity type could not be inferred for ID: %s", idStr)
 		}
 		*el = append(*el, Entity{Type: eType, ID: idStr})
+		have.add(setKey{typ: eType, id: idStr})
 	}
 	return el, nil
 
@@ -85,14 +83,7 @@ func (el *EntityList) AppendEntityByID(id ...string) (*EntityList, error) {
 
 // RemoveEntityByID removes one or more Entities from the referenced EntityList by ID string
 func (el *EntityList) RemoveEntityByID(id ...string) (*EntityList, error) {
-	for _, idStr := range id {
-		for i, e := range *el {
-			if e.ID == idStr {
-				*el = append((*el)[:i], (*el)[i+1:]...)
-				break
-			}
-		}
-	}
+	*el = removeIDs(*el, id)
 	return el, nil
 }
 
@@ -132,14 +123,12 @@ func (el EntityList) Match(eType string) *EntityList {
 	return &newList
 }
 
-// Contains returns true if the EntityList contains the given Entity ID
+// Contains returns true if the EntityList contains the given Entity ID.
+// Long lists are indexed the first time they are checked, so later calls on the same list
+// take constant time. The index follows appends, removals and reordering; after assigning
+// a different entity to an element in place, use a copy of the list or build an Index.
 func (el EntityList) Contains(id string) bool {
-	for _, e := range el {
-		if e.ID == id {
-			return true
-		}
-	}
-	return false
+	return entityIndexes.contains(el, id)
 }
 
 // inferredEType returns a rough guess of the entity type for the given Entity ID string
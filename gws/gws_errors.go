@@ -1,12 +1,29 @@
 package gws
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Sentinel errors for common API failure classes. An *APIError matches these
+// with errors.Is, so callers can branch on the failure without matching strings.
+var (
+	ErrNotFound           = errors.New("gws: not found")
+	ErrUnauthorized       = errors.New("gws: unauthorized")
+	ErrPreconditionFailed = errors.New("gws: precondition failed")
+	ErrConflict           = errors.New("gws: conflict")
 )
 
-// apiError describes an error returned by the API.
-type apiError struct {
+// ErrMissingETag is returned by UpdateGroup for a group without an ETag, unless WithForce
+// is given. Without the ETag the update would overwrite changes made since the group was read.
+var ErrMissingETag = errors.New("gws: group has no ETag")
+
+// ErrorDetail describes a single error entry returned by the API.
+type ErrorDetail struct {
 	Status    int      `json:"status"`
 	SubStatus string   `json:"subStatus"`
 	Detail    []string `json:"detail"`
@@ -35,26 +52,148 @@ type errorResponse struct {
 	}
 
 	// Errors describe errors that occurred
-	Errors []apiError
+	Errors []ErrorDetail
 }
 
-// formatErrorResponse extracts the API error into an error
-func formatErrorResponse(er *errorResponse) error {
+// notFound collects the notFound IDs from every error entry.
+// Membership PUTs report unknown IDs this way on an otherwise successful response.
+func (er *errorResponse) notFound() []string {
 	if er == nil {
-		return fmt.Errorf("API error: empty response")
+		return nil
 	}
-	if len(er.Errors) == 0 {
-		return fmt.Errorf("API error: no error details provided")
+	var ids []string
+	for _, e := range er.Errors {
+		ids = append(ids, e.NotFound...)
 	}
-	e := er.Errors[0]
+	return ids
+}
+
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Method and Path identify the request that failed
+	Method string
+	Path   string
+
+	// Status and SubStatus are taken from the first error entry in the response body
+	Status    int
+	SubStatus string
+
+	// Detail and NotFound are gathered from every error entry in the response body
+	Detail   []string
+	NotFound []string
+
+	// Errors holds every error entry as returned by the API
+	Errors []ErrorDetail
+}
+
+// Error renders the status and details of the API error.
+func (e *APIError) Error() string {
 	if len(e.Detail) == 0 {
-		return fmt.Errorf("API error status %d", e.Status)
+		return fmt.Sprintf("API error status %d", e.status())
+	}
+	return fmt.Sprintf("API error status %d: %s", e.status(), strings.Join(e.Detail, ", "))
+}
+
+// Is reports whether the API error belongs to the class of the target sentinel error.
+func (e *APIError) Is(target error) bool {
+	switch e.status() {
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	case http.StatusPreconditionFailed:
+		return target == ErrPreconditionFailed
+	case http.StatusConflict:
+		return target == ErrConflict
+	}
+	return false
+}
+
+// status prefers the status in the response body, falling back to the HTTP status.
+func (e *APIError) status() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return e.StatusCode
+}
+
+// notFoundSubject tells what a 404 is about from its detail text: "member" when a detail
+// names the member, "group" when one names only the group, and "" when the text does not
+// say or err is not a 404. The API answers 404 for both a missing member and a missing group.
+func notFoundSubject(err error) string {
+	var e *APIError
+	if !errors.As(err, &e) || !errors.Is(e, ErrNotFound) {
+		return ""
+	}
+	subject := ""
+	for _, d := range e.Detail {
+		d = strings.ToLower(d)
+		switch {
+		case strings.Contains(d, "member"):
+			subject = "member"
+		case strings.Contains(d, "group"):
+			return "group"
+		}
+	}
+	return subject
+}
+
+// isMemberNotFound reports whether err is a 404 that names the member rather than the group.
+func isMemberNotFound(err error) bool {
+	return notFoundSubject(err) == "member"
+}
+
+// isMaybeMemberNotFound reports whether err is a 404 that may be about the member, one
+// that names the member or does not say.
+func isMaybeMemberNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) && notFoundSubject(err) != "group"
+}
+
+// memberNotFoundError is used when a member request succeeds but returns no member.
+func memberNotFoundError(resp *resty.Response) error {
+	e := newAPIError(resp).(*APIError)
+	e.Status = http.StatusNotFound
+	e.Detail = []string{"Member not found"}
+	return e
+}
+
+// newAPIError builds an *APIError from a failed response.
+func newAPIError(resp *resty.Response) error {
+	e := &APIError{StatusCode: resp.StatusCode()}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		if resp.Request.RawRequest != nil {
+			e.Path = resp.Request.RawRequest.URL.Path
+		} else {
+			e.Path = resp.Request.URL
+		}
+	}
+	er, _ := resp.Error().(*errorResponse)
+	return e.withBody(er)
+}
+
+// withBody fills in the details of the error from the parsed response body, if any.
+func (e *APIError) withBody(er *errorResponse) error {
+	if er == nil {
+		return e
+	}
+	e.Errors = er.Errors
+	if len(er.Errors) > 0 {
+		e.Status = er.Errors[0].Status
+		e.SubStatus = er.Errors[0].SubStatus
+	}
+	for _, d := range er.Errors {
+		e.Detail = append(e.Detail, d.Detail...)
+		e.NotFound = append(e.NotFound, d.NotFound...)
 	}
-	return fmt.Errorf("API error status %d: %s", e.Status, strings.Join(e.Detail, ", "))
+	return e
 }
 
 // SAMPLES
-// apiError
+// ErrorDetail
 // 	  {
 // 		"status": 401,
 // 		"detail": [
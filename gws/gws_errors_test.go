@@ -0,0 +1,42 @@
+package gws
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNotFoundClassification(t *testing.T) {
+	notFound := func(detail ...string) error {
+		return &APIError{StatusCode: http.StatusNotFound, Status: http.StatusNotFound, Detail: detail}
+	}
+	for _, tc := range []struct {
+		name          string
+		err           error
+		subject       string
+		member, maybe bool
+	}{
+		{"member detail", notFound("Member not found"), "member", true, true},
+		{"member in group detail", notFound("Member joeuser not found in group u_test"), "member", true, true},
+		{"group detail", notFound("Group not found"), "group", false, false},
+		{"group and member details", notFound("Member not found", "Group not found"), "group", false, false},
+		{"no detail", notFound(), "", false, true},
+		{"unrelated detail", notFound("Not found"), "", false, true},
+		{"wrapped", fmt.Errorf("lookup: %w", notFound("Member not found")), "member", true, true},
+		{"other status", &APIError{StatusCode: http.StatusForbidden, Detail: []string{"Member access denied"}}, "", false, false},
+		{"not an API error", errors.New("member not found"), "", false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := notFoundSubject(tc.err); got != tc.subject {
+				t.Errorf("notFoundSubject = %q, want %q", got, tc.subject)
+			}
+			if got := isMemberNotFound(tc.err); got != tc.member {
+				t.Errorf("isMemberNotFound = %v, want %v", got, tc.member)
+			}
+			if got := isMaybeMemberNotFound(tc.err); got != tc.maybe {
+				t.Errorf("isMaybeMemberNotFound = %v, want %v", got, tc.maybe)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package gws
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ExpandOptions controls ExpandMembership.
+type ExpandOptions struct {
+	// MaxDepth is how many levels of groups are read, counting the group being expanded,
+	// along the shortest path to each group. Groups nested deeper are listed in
+	// Expansion.Truncated. Zero means unlimited.
+	MaxDepth int
+
+	// MaxPaths is how many inclusion paths are listed per member in ExpandedMember.Paths,
+	// zero means 10. All paths are still counted in ExpandedMember.PathCount.
+	MaxPaths int
+
+	// Concurrency is how many group memberships are fetched at once, zero or one means
+	// one after another.
+	Concurrency int
+}
+
+// ExpandedMember is an effective member of a group together with how it got there.
+type ExpandedMember struct {
+	// Member has MType set to direct or indirect, and Source to the comma joined
+	// groups that hold it directly when it is indirect.
+	Member
+
+	// Paths lists up to ExpandOptions.MaxPaths chains of group IDs through which the member
+	// is included, starting with the expanded group and ending with the group that holds the
+	// member directly.
+	Paths [][]string
+
+	// PathCount is the number of inclusion paths, which may be far more than are listed in
+	// Paths. Paths that pass through a cycle are not counted. It stops at the largest int.
+	PathCount int
+}
+
+// Expansion is the result of ExpandMembership.
+type Expansion struct {
+	// GroupID is the expanded group
+	GroupID string
+
+	// Members are the non-group effective members in the order first found
+	Members []ExpandedMember
+
+	// Cycles are inclusion paths that lead back to a group already on the path, one for
+	// each nested group membership closing a cycle. The last element repeats the group
+	// closing the cycle.
+	Cycles [][]string
+
+	// Truncated are nested groups that were not expanded because of MaxDepth
+	Truncated []string
+
+	// Errors holds nested groups whose membership could not be read, by group ID
+	Errors map[string]error
+}
+
+// Member returns the expanded member with the given ID, if it is an effective member.
+func (e *Expansion) Member(id string) (*ExpandedMember, bool) {
+	for i := range e.Members {
+		if e.Members[i].ID == id {
+			return &e.Members[i], true
+		}
+	}
+	return nil, false
+}
+
+// ExpandMembership walks the direct membership of the group recursively through nested
+// groups on the client side and returns every effective member with the paths through
+// which it is included. Unlike GetEffectiveMembership this answers why someone is a member.
+// opts may be nil.
+func (client *Client) ExpandMembership(groupid string, opts *ExpandOptions) (*Expansion, error) {
+	return client.ExpandMembershipContext(context.Background(), groupid, opts)
+}
+
+// ExpandMembershipContext is like ExpandMembership but carries ctx through to the API requests.
+func (client *Client) ExpandMembershipContext(ctx context.Context, groupid string, opts *ExpandOptions) (*Expansion, error) {
+	return invokeResult(client, ctx, "ExpandMembership", groupid, func(ctx context.Context) (*Expansion, error) {
+		if opts == nil {
+			opts = &ExpandOptions{}
+		}
+		exp := &Expansion{GroupID: groupid, Errors: make(map[string]error)}
+
+		memberships, err := client.fetchNested(ctx, []string{groupid}, opts.MaxDepth, opts.Concurrency, exp.Errors)
+		if err != nil {
+			return nil, err
+		}
+		if err := exp.Errors[groupid]; err != nil {
+			return nil, err
+		}
+
+		g := expandGraph{
+			exp:         exp,
+			memberships: memberships,
+			state:       make(map[string]int),
+			parents:     make(map[string][]string),
+			holders:     make(map[Member][]string),
+			index:       make(map[Member]int),
+			seenCycle:   make(map[string]bool),
+		}
+		g.visit(groupid, nil)
+
+		maxPaths := opts.MaxPaths
+		if maxPaths <= 0 {
+			maxPaths = defaultMaxPaths
+		}
+		counts := g.countPaths(groupid)
+		paths := make(map[string][][]string)
+		for i := range exp.Members {
+			em := &exp.Members[i]
+			holders := g.holders[em.Member]
+			em.MType = "indirect"
+			if slices.Contains(holders, groupid) {
+				em.MType = "direct"
+			} else {
+				em.Source = strings.Join(holders, ",")
+			}
+			for _, holder := range holders {
+				em.PathCount = saturatingAdd(em.PathCount, counts[holder])
+				if len(em.Paths) >= maxPaths {
+					continue
+				}
+				if _, ok := paths[holder]; !ok {
+					paths[holder] = listPaths(g.parents, holder, maxPaths)
+				}
+				for _, p := range paths[holder][:min(len(paths[holder]), maxPaths-len(em.Paths))] {
+					em.Paths = append(em.Paths, slices.Clone(p))
+				}
+			}
+		}
+		return exp, nil
+	})
+}
+
+// expandGraph walks the groups under the expanded group once, depth first, as nestingGraph
+// does. Memberships that close a cycle are reported and then left out, and paths are counted
+// and listed on the DAG that remains instead of following every path through it.
+type expandGraph struct {
+	exp         *Expansion
+	memberships map[string]MemberList
+
+	state     map[string]int
+	order     []string            // groups in DFS post-order
+	parents   map[string][]string // DAG predecessors of each group
+	holders   map[Member][]string // groups holding each member directly
+	index     map[Member]int      // position of each member in exp.Members
+	seenCycle map[string]bool
+}
+
+// visit records the members of gid and walks the groups nested under it, stack holding the
+// groups above it.
+func (g *expandGraph) visit(gid string, stack []string) {
+	g.state[gid] = onStack
+	stack = append(stack, gid)
+	for _, m := range g.memberships[gid] {
+		if m.Type != MemberTypeGroup {
+			key := Member{Type: m.Type, ID: m.ID}
+			if _, ok := g.index[key]; !ok {
+				g.index[key] = len(g.exp.Members)
+				g.exp.Members = append(g.exp.Members, ExpandedMember{Member: key})
+			}
+			if !slices.Contains(g.holders[key], gid) {
+				g.holders[key] = append(g.holders[key], gid)
+			}
+			continue
+		}
+
+		switch g.state[m.ID] {
+		case onStack:
+			cycle := append(slices.Clone(stack), m.ID)
+			if key := strings.Join(cycle, ","); !g.seenCycle[key] {
+				g.seenCycle[key] = true
+				g.exp.Cycles = append(g.exp.Cycles, cycle)
+			}
+			continue
+		case unvisited:
+			if _, fetched := g.memberships[m.ID]; !fetched {
+				if g.exp.Errors[m.ID] == nil && !slices.Contains(g.exp.Truncated, m.ID) {
+					g.exp.Truncated = append(g.exp.Truncated, m.ID)
+				}
+				continue
+			}
+			g.visit(m.ID, stack)
+		}
+		if !slices.Contains(g.parents[m.ID], gid) {
+			g.parents[m.ID] = append(g.parents[m.ID], gid)
+		}
+	}
+	g.state[gid] = finished
+	g.order = append(g.order, gid)
+}
+
+// countPaths returns the number of inclusion paths from root to every group walked,
+// computed in topological order.
+func (g *expandGraph) countPaths(root string) map[string]int {
+	counts := map[string]int{root: 1}
+	for i := len(g.order) - 1; i >= 0; i-- {
+		gid := g.order[i]
+		for _, p := range g.parents[gid] {
+			counts[gid] = saturatingAdd(counts[gid], counts[p])
+		}
+	}
+	return counts
+}
+
+// fetchNested fetches the direct membership of the roots and of every group nested under them
+// within maxDepth, one level at a time with up to concurrency requests at once. Groups whose
+// membership cannot be read are recorded in errs; only cancellation of ctx is returned.
+func (client *Client) fetchNested(ctx context.Context, roots []string, maxDepth, concurrency int, errs map[string]error) (map[string]MemberList, error) {
+	memberships := make(map[string]MemberList)
+	level := slices.Clone(roots)
+	for depth := 1; len(level) > 0; depth++ {
+		results := make([]MemberList, len(level))
+		failures := make([]error, len(level))
+		fetch := func(i int) {
+			ml, err := client.GetMembershipContext(ctx, level[i])
+			if err != nil {
+				failures[i] = err
+				return
+			}
+			results[i] = *ml
+		}
+
+		if concurrency > 1 {
+			sem := make(chan struct{}, concurrency)
+			var wg sync.WaitGroup
+			for i := range level {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer func() { <-sem; wg.Done() }()
+					fetch(i)
+				}()
+			}
+			wg.Wait()
+		} else {
+			for i := range level {
+				fetch(i)
+			}
+		}
+
+		for i, gid := range level {
+			if failures[i] != nil {
+				errs[gid] = failures[i]
+			} else {
+				memberships[gid] = results[i]
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var next []string
+		if maxDepth == 0 || depth < maxDepth {
+			for _, gid := range level {
+				for _, m := range memberships[gid] {
+					if m.Type != MemberTypeGroup || slices.Contains(next, m.ID) {
+						continue
+					}
+					if _, done := memberships[m.ID]; done || errs[m.ID] != nil {
+						continue
+					}
+					next = append(next, m.ID)
+				}
+			}
+		}
+		level = next
+	}
+	return memberships, nil
+}
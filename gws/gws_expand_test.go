@@ -0,0 +1,190 @@
+package gws_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// addGroupOf adds group id to srv with the given members, group IDs starting with u_.
+func addGroupOf(srv *gwstest.Server, id string, ids ...string) {
+	members := make([]gws.Member, 0, len(ids))
+	for _, m := range ids {
+		typ := gws.MemberTypeUWNetID
+		if strings.HasPrefix(m, "u_") {
+			typ = gws.MemberTypeGroup
+		}
+		members = append(members, gws.Member{Type: typ, ID: m})
+	}
+	srv.AddGroup(gws.Group{ID: id}, members...)
+}
+
+func TestExpandMembership(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		groups map[string][]string
+		opts   *gws.ExpandOptions
+		member string
+		mtype  string
+		source string
+		paths  [][]string
+		count  int
+		cycles [][]string
+		trunc  []string
+	}{
+		{
+			name:   "direct",
+			groups: map[string][]string{"u_root": {"joeuser"}},
+			member: "joeuser", mtype: "direct",
+			paths: [][]string{{"u_root"}}, count: 1,
+		},
+		{
+			name: "diamond",
+			groups: map[string][]string{
+				"u_root": {"u_a", "u_b"}, "u_a": {"u_c"}, "u_b": {"u_c"}, "u_c": {"joeuser"},
+			},
+			member: "joeuser", mtype: "indirect", source: "u_c",
+			paths: [][]string{{"u_root", "u_a", "u_c"}, {"u_root", "u_b", "u_c"}}, count: 2,
+		},
+		{
+			name: "direct and nested",
+			groups: map[string][]string{
+				"u_root": {"u_a", "joeuser"}, "u_a": {"joeuser"},
+			},
+			member: "joeuser", mtype: "direct",
+			paths: [][]string{{"u_root", "u_a"}, {"u_root"}}, count: 2,
+		},
+		{
+			name: "several holders",
+			groups: map[string][]string{
+				"u_root": {"u_a", "u_b"}, "u_a": {"joeuser"}, "u_b": {"joeuser"},
+			},
+			member: "joeuser", mtype: "indirect", source: "u_a,u_b",
+			paths: [][]string{{"u_root", "u_a"}, {"u_root", "u_b"}}, count: 2,
+		},
+		{
+			name: "cycle",
+			groups: map[string][]string{
+				"u_root": {"u_a"}, "u_a": {"u_b"}, "u_b": {"u_a", "joeuser"},
+			},
+			member: "joeuser", mtype: "indirect", source: "u_b",
+			paths: [][]string{{"u_root", "u_a", "u_b"}}, count: 1,
+			cycles: [][]string{{"u_root", "u_a", "u_b", "u_a"}},
+		},
+		{
+			name: "self inclusion",
+			groups: map[string][]string{
+				"u_root": {"u_root", "joeuser"},
+			},
+			member: "joeuser", mtype: "direct",
+			paths: [][]string{{"u_root"}}, count: 1,
+			cycles: [][]string{{"u_root", "u_root"}},
+		},
+		{
+			name: "MaxPaths",
+			groups: map[string][]string{
+				"u_root": {"u_a", "u_b", "u_c"}, "u_a": {"u_d"}, "u_b": {"u_d"}, "u_c": {"u_d"}, "u_d": {"joeuser"},
+			},
+			opts:   &gws.ExpandOptions{MaxPaths: 2},
+			member: "joeuser", mtype: "indirect", source: "u_d",
+			paths: [][]string{{"u_root", "u_a", "u_d"}, {"u_root", "u_b", "u_d"}}, count: 3,
+		},
+		{
+			name: "MaxDepth",
+			groups: map[string][]string{
+				"u_root": {"u_a"}, "u_a": {"u_b", "joeuser"}, "u_b": {"janeuser"},
+			},
+			opts:   &gws.ExpandOptions{MaxDepth: 2},
+			member: "joeuser", mtype: "indirect", source: "u_a",
+			paths: [][]string{{"u_root", "u_a"}}, count: 1,
+			trunc: []string{"u_b"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := gwstest.NewServer()
+			defer srv.Close()
+			for id, members := range tc.groups {
+				addGroupOf(srv, id, members...)
+			}
+			client, err := gws.NewClient(srv.Config())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exp, err := client.ExpandMembership("u_root", tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, ok := exp.Member(tc.member)
+			if !ok {
+				t.Fatalf("%s is not in the expansion", tc.member)
+			}
+			if m.MType != tc.mtype || m.Source != tc.source {
+				t.Errorf("MType, Source = %q, %q; want %q, %q", m.MType, m.Source, tc.mtype, tc.source)
+			}
+			if !slices.EqualFunc(m.Paths, tc.paths, slices.Equal) || m.PathCount != tc.count {
+				t.Errorf("Paths = %v of %d, want %v of %d", m.Paths, m.PathCount, tc.paths, tc.count)
+			}
+			if !slices.EqualFunc(exp.Cycles, tc.cycles, slices.Equal) {
+				t.Errorf("Cycles = %v, want %v", exp.Cycles, tc.cycles)
+			}
+			if !slices.Equal(exp.Truncated, tc.trunc) {
+				t.Errorf("Truncated = %v, want %v", exp.Truncated, tc.trunc)
+			}
+			if _, ok := exp.Member("janeuser"); ok {
+				t.Error("janeuser of a truncated group is in the expansion")
+			}
+		})
+	}
+}
+
+func TestExpandMembershipLayeredGraph(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+
+	// As in TestAnalyzeNestingLayeredGraph, 2^(layers-1) paths lead to each group of the last
+	// layer, far too many to walk one by one
+	const layers = 40
+	layer := func(i int) []string { return []string{fmt.Sprintf("u_l%02d_a", i), fmt.Sprintf("u_l%02d_b", i)} }
+	addGroupOf(srv, fmt.Sprintf("u_l%02d_a", layers), "joeuser")
+	addGroupOf(srv, fmt.Sprintf("u_l%02d_b", layers), "joeuser")
+	for i := layers - 1; i >= 1; i-- {
+		for _, gid := range layer(i) {
+			addGroupOf(srv, gid, layer(i+1)...)
+		}
+	}
+	addGroupOf(srv, "u_root", layer(1)...)
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	exp, err := client.ExpandMembership("u_root", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("ExpandMembership took %v", d)
+	}
+	m, ok := exp.Member("joeuser")
+	if !ok {
+		t.Fatal("joeuser is not in the expansion")
+	}
+	if want := 1 << layers; m.PathCount != want {
+		t.Errorf("PathCount = %d, want %d", m.PathCount, want)
+	}
+	if len(m.Paths) != 10 {
+		t.Errorf("listed %d paths, want the default of 10", len(m.Paths))
+	}
+	for _, path := range m.Paths {
+		if len(path) != layers+1 || path[0] != "u_root" {
+			t.Errorf("path %v does not lead from u_root through every layer", path)
+		}
+	}
+}
@@ -1,6 +1,15 @@
 package gws
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+)
 
 // Group defines a group, except for membership.
 type Group struct {
@@ -187,19 +196,127 @@ func (dc DataClassification) String() string {
 
 // GetGroup returns the group identified by the groupid.
 func (client *Client) GetGroup(groupid string) (*Group, error) {
-	resp, err := client.request().
-		SetResult(groupResponse{}).
-		Get(fmt.Sprintf("/group/%s", groupid))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+	return client.GetGroupContext(context.Background(), groupid)
+}
+
+// GetGroupContext is like GetGroup but carries ctx through to the API request.
+func (client *Client) GetGroupContext(ctx context.Context, groupid string) (*Group, error) {
+	return invokeResult(client, ctx, "GetGroup", groupid, func(ctx context.Context) (*Group, error) {
+		key := cacheKey{kind: cacheGroup, group: groupid}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			if cached.err != nil {
+				return nil, cached.err
+			}
+			return cached.value.(*Group).Clone(), nil
+		}
+
+		req := client.request(ctx).
+			SetResult(groupResponse{}).
+			SetPathParam("groupid", groupid)
+		if cached != nil && cached.value.(*Group).etag != "" {
+			req.SetHeader("If-None-Match", cached.value.(*Group).etag)
+		}
+		resp, err := req.Get("/group/{groupid}")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode() == http.StatusNotModified && cached != nil {
+			client.cache.revalidated(key, gen)
+			return cached.value.(*Group).Clone(), nil
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+
+		group := resp.Result().(*groupResponse).Data
+		group.etag = resp.Header().Get("Etag")
+		client.cache.put(key, gen, group.Clone(), nil)
+		return &group, nil
+	})
+}
+
+// GetGroupIfChanged returns the group if its ETag differs from etag. When the group is
+// unchanged it returns a nil group and false, at the cost of a 304 Not Modified response.
+func (client *Client) GetGroupIfChanged(groupid string, etag string) (*Group, bool, error) {
+	return client.GetGroupIfChangedContext(context.Background(), groupid, etag)
+}
+
+// GetGroupIfChangedContext is like GetGroupIfChanged but carries ctx through to the API request.
+func (client *Client) GetGroupIfChangedContext(ctx context.Context, groupid string, etag string) (*Group, bool, error) {
+	var changed bool
+	group, err := invokeResult(client, ctx, "GetGroupIfChanged", groupid, func(ctx context.Context) (*Group, error) {
+		key := cacheKey{kind: cacheGroup, group: groupid}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			if cached.err != nil {
+				return nil, cached.err
+			}
+			if g := cached.value.(*Group); g.etag != etag {
+				changed = true
+				return g.Clone(), nil
+			}
+			return nil, nil
+		}
+
+		req := client.request(ctx).
+			SetResult(groupResponse{}).
+			SetPathParam("groupid", groupid)
+		if etag != "" {
+			req.SetHeader("If-None-Match", etag)
+		}
+		resp, err := req.Get("/group/{groupid}")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode() == http.StatusNotModified {
+			if cached != nil && cached.value.(*Group).etag == etag {
+				// The expired entry is the unchanged group
+				client.cache.revalidated(key, gen)
+			}
+			return nil, nil
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+
+		group := resp.Result().(*groupResponse).Data
+		group.etag = resp.Header().Get("Etag")
+		client.cache.put(key, gen, group.Clone(), nil)
+		changed = true
+		return &group, nil
+	})
+	return group, changed, err
+}
+
+// Clone returns a copy of the group that shares no entity lists with it, so either can be
+// changed freely. Take one before editing a group to diff the result against it.
+func (group *Group) Clone() *Group {
+	c := *group
+	c.Admins = slices.Clone(group.Admins)
+	c.Updaters = slices.Clone(group.Updaters)
+	c.Creators = slices.Clone(group.Creators)
+	c.Readers = slices.Clone(group.Readers)
+	c.Optins = slices.Clone(group.Optins)
+	c.Optouts = slices.Clone(group.Optouts)
+	return &c
+}
+
+// ETag returns the entity tag the group arrived with, empty for a group built locally.
+// Store it alongside a serialized group to keep optimistic concurrency across a round trip.
+func (group *Group) ETag() string {
+	return group.etag
+}
 
-	group := resp.Result().(*groupResponse).Data
-	group.etag = resp.Header().Get("Etag")
-	return &group, nil
+// WithETag sets the entity tag sent as If-Match when the group is updated, for a group
+// restored from JSON or another store, and returns the group.
+func (group *Group) WithETag(etag string) *Group {
+	group.etag = etag
+	return group
 }
 
 // HistoryOrder defines the order of history entries
@@ -281,104 +398,206 @@ func (opts *HistoryOptions) WithMemberID(memberID string) *HistoryOptions {
 // The options parameter can be used to filter and order the results. If options is nil,
 // default server settings are used.
 func (client *Client) GetHistory(groupid string, options *HistoryOptions) (*History, error) {
-	if groupid == "" {
-		return nil, fmt.Errorf("groupid cannot be empty")
-	}
-
-	req := client.request().
-		SetResult(History{})
+	return client.GetHistoryContext(context.Background(), groupid, options)
+}
 
-	// Add query parameters based on options
-	if options != nil {
-		if options.StartTime > 0 {
-			req.SetQueryParam("start", fmt.Sprintf("%d", options.StartTime))
+// GetHistoryContext is like GetHistory but carries ctx through to the API request.
+func (client *Client) GetHistoryContext(ctx context.Context, groupid string, options *HistoryOptions) (*History, error) {
+	return invokeResult(client, ctx, "GetHistory", groupid, func(ctx context.Context) (*History, error) {
+		if groupid == "" {
+			return nil, fmt.Errorf("groupid cannot be empty")
 		}
 
-		if options.MaxResults > 0 {
-			req.SetQueryParam("size", fmt.Sprintf("%d", options.MaxResults))
-		}
+		req := client.request(ctx).
+			SetResult(History{})
 
-		if options.Order != "" {
-			req.SetQueryParam("order", string(options.Order))
-		}
+		// Add query parameters based on options
+		if options != nil {
+			if options.StartTime > 0 {
+				req.SetQueryParam("start", fmt.Sprintf("%d", options.StartTime))
+			}
 
-		if options.ActivityType != "" {
-			req.SetQueryParam("activity", string(options.ActivityType))
-		}
+			if options.MaxResults > 0 {
+				req.SetQueryParam("size", fmt.Sprintf("%d", options.MaxResults))
+			}
+
+			if options.Order != "" {
+				req.SetQueryParam("order", string(options.Order))
+			}
+
+			if options.ActivityType != "" {
+				req.SetQueryParam("activity", string(options.ActivityType))
+			}
 
-		if options.MemberID != "" {
-			req.SetQueryParam("id", options.MemberID)
+			if options.MemberID != "" {
+				req.SetQueryParam("id", options.MemberID)
+			}
 		}
-	}
 
-	resp, err := req.Get(fmt.Sprintf("/group/%s/history", groupid))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+		resp, err := req.
+			SetPathParam("groupid", groupid).
+			Get("/group/{groupid}/history")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, newAPIError(resp)
+		}
 
-	history := resp.Result().(*History)
-	return history, nil
+		history := resp.Result().(*History)
+		return history, nil
+	})
 }
 
 // CreateGroup creates a new group as defined by the specified Group.
-func (client *Client) CreateGroup(newgroup *Group) (*Group, error) {
-	groupid := newgroup.ID
-	body := &putGroup{Data: *newgroup}
-
-	resp, err := client.request().
-		SetBody(body).
-		SetQueryString(client.syncQueryString()).
-		SetResult(groupResponse{}).
-		Put(fmt.Sprintf("/group/%s", groupid))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+func (client *Client) CreateGroup(newgroup *Group, opts ...RequestOption) (*Group, error) {
+	return client.CreateGroupContext(context.Background(), newgroup, opts...)
+}
+
+// CreateGroupContext is like CreateGroup but carries ctx through to the API request.
+func (client *Client) CreateGroupContext(ctx context.Context, newgroup *Group, opts ...RequestOption) (*Group, error) {
+	return invokeResult(client, ctx, "CreateGroup", newgroup.ID, func(ctx context.Context) (*Group, error) {
+		groupid := newgroup.ID
+		defer client.cache.invalidate(false, groupid)
+		body := &putGroup{Data: *newgroup}
+
+		resp, err := client.writeRequest(ctx, opts).
+			SetBody(body).
+			SetResult(groupResponse{}).
+			SetPathParam("groupid", groupid).
+			Put("/group/{groupid}")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, newAPIError(resp)
+		}
 
-	group := resp.Result().(*groupResponse).Data
-	group.etag = resp.Header().Get("Etag")
-	return &group, nil
+		group := resp.Result().(*groupResponse).Data
+		group.etag = resp.Header().Get("Etag")
+		return &group, nil
+	})
 }
 
 // UpdateGroup updates an existing Group to match the specified Group.
-func (client *Client) UpdateGroup(modgroup *Group) (*Group, error) {
-	groupid := modgroup.ID
-	body := &putGroup{Data: *modgroup}
-
-	resp, err := client.request().
-		SetHeader("If-Match", modgroup.etag).
-		SetQueryString(client.syncQueryString()).
-		SetBody(body).
-		SetResult(groupResponse{}).
-		Put(fmt.Sprintf("/group/%s", groupid))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+// The ETag of modgroup is sent as If-Match unless overridden with WithIfMatch. A group
+// without an ETag is refused with ErrMissingETag unless WithForce is given.
+func (client *Client) UpdateGroup(modgroup *Group, opts ...RequestOption) (*Group, error) {
+	return client.UpdateGroupContext(context.Background(), modgroup, opts...)
+}
+
+// UpdateGroupContext is like UpdateGroup but carries ctx through to the API request.
+func (client *Client) UpdateGroupContext(ctx context.Context, modgroup *Group, opts ...RequestOption) (*Group, error) {
+	return invokeResult(client, ctx, "UpdateGroup", modgroup.ID, func(ctx context.Context) (*Group, error) {
+		groupid := modgroup.ID
+		defer client.cache.invalidate(false, groupid)
+		body := &putGroup{Data: *modgroup}
+		// Kept apart from opts so a middleware that calls next again starts from the caller's options
+		etagOpts := append([]RequestOption{WithIfMatch(modgroup.etag)}, opts...)
+		if o := client.resolveOptions(etagOpts); o.ifMatch != "" {
+			// A repeat of an update that went through fails the If-Match check
+			ctx = withRetrySafe(ctx)
+		} else if !o.force {
+			return nil, fmt.Errorf("%w: cannot update %s, fetch it first or use WithForce", ErrMissingETag, groupid)
+		}
 
-	group := resp.Result().(*groupResponse).Data
-	group.etag = resp.Header().Get("Etag")
-	return &group, nil
+		resp, err := client.writeRequest(ctx, etagOpts).
+			SetBody(body).
+			SetResult(groupResponse{}).
+			SetPathParam("groupid", groupid).
+			Put("/group/{groupid}")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, newAPIError(resp)
+		}
+
+		group := resp.Result().(*groupResponse).Data
+		group.etag = resp.Header().Get("Etag")
+		return &group, nil
+	})
 }
 
 // DeleteGroup deletes the Group identified by the specified group id.
-func (client *Client) DeleteGroup(groupid string) error {
-	resp, err := client.request().
-		Delete(fmt.Sprintf("/group/%s", groupid))
-	if err != nil {
-		return err
-	}
-	if resp.IsError() {
-		return formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return nil
+func (client *Client) DeleteGroup(groupid string, opts ...RequestOption) error {
+	return client.DeleteGroupContext(context.Background(), groupid, opts...)
+}
+
+// DeleteGroupContext is like DeleteGroup but carries ctx through to the API request.
+func (client *Client) DeleteGroupContext(ctx context.Context, groupid string, opts ...RequestOption) error {
+	return client.invoke(ctx, "DeleteGroup", groupid, func(ctx context.Context) error {
+		defer client.cache.invalidate(true, groupid)
+		resp, err := client.writeRequest(ctx, opts).
+			SetPathParam("groupid", groupid).
+			Delete("/group/{groupid}")
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return newAPIError(resp)
+		}
+		return nil
+	})
+}
+
+// defaultModifyAttempts is the number of attempts ModifyGroup makes when Config.ModifyAttempts is zero.
+const defaultModifyAttempts = 5
+
+// ModifyGroup applies mutate to the current state of the group and saves the result,
+// guarded by the group's ETag. If someone else changes the group in between, the update
+// fails with 412 Precondition Failed; ModifyGroup then fetches the group again and
+// re-applies mutate, up to Config.ModifyAttempts times in all. mutate must therefore be
+// safe to call more than once. An error from mutate stops ModifyGroup without saving,
+// and if mutate leaves the group unchanged nothing is sent.
+func (client *Client) ModifyGroup(groupid string, mutate func(*Group) error, opts ...RequestOption) (*Group, error) {
+	return client.ModifyGroupContext(context.Background(), groupid, mutate, opts...)
+}
+
+// ModifyGroupContext is like ModifyGroup but carries ctx through to the API requests.
+func (client *Client) ModifyGroupContext(ctx context.Context, groupid string, mutate func(*Group) error, opts ...RequestOption) (*Group, error) {
+	return invokeResult(client, ctx, "ModifyGroup", groupid, func(ctx context.Context) (*Group, error) {
+		attempts := client.config.ModifyAttempts
+		if attempts <= 0 {
+			attempts = defaultModifyAttempts
+		}
+
+		var err error
+		for attempt := 1; attempt <= attempts; attempt++ {
+			if attempt > 1 {
+				// Spread out writers racing on the same group
+				wait := time.Duration(attempt-1)*50*time.Millisecond + time.Duration(rand.Int64N(int64(50*time.Millisecond)))
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+
+			var current *Group
+			current, err = client.GetGroupContext(ctx, groupid)
+			if err != nil {
+				return nil, err
+			}
+			group := current.Clone()
+			if err := mutate(group); err != nil {
+				return nil, err
+			}
+			if reflect.DeepEqual(group, current) {
+				return group, nil
+			}
+
+			var updated *Group
+			updated, err = client.UpdateGroupContext(ctx, group, opts...)
+			if err == nil {
+				return updated, nil
+			}
+			if !errors.Is(err, ErrPreconditionFailed) {
+				return nil, err
+			}
+		}
+		return nil, fmt.Errorf("group %s kept changing, gave up after %d attempts: %w", groupid, attempts, err)
+	})
 }
 
 // SetAuthnFactor sets the multi-factor authn required for the group
@@ -550,64 +769,84 @@ func (group *Group) IsOptout(id string) bool {
 // The groupID argument may be a group name or a regid. The method first resolves the
 // group's regid and then performs the move using /groupMove/{regid}?newext=...
 func (client *Client) RenameGroup(groupID string, newLeaf string) error {
-	if groupID == "" {
-		return fmt.Errorf("groupID cannot be empty")
-	}
-	if newLeaf == "" {
-		return fmt.Errorf("newLeaf cannot be empty")
-	}
+	return client.RenameGroupContext(context.Background(), groupID, newLeaf)
+}
 
-	// Resolve regid for idempotent move
-	grp, err := client.GetGroup(groupID)
-	if err != nil {
-		return err
-	}
-	regid := grp.Regid
-	if regid == "" {
-		return fmt.Errorf("could not resolve group regid")
-	}
+// RenameGroupContext is like RenameGroup but carries ctx through to the API requests.
+func (client *Client) RenameGroupContext(ctx context.Context, groupID string, newLeaf string) error {
+	return client.invoke(ctx, "RenameGroup", groupID, func(ctx context.Context) error {
+		if groupID == "" {
+			return fmt.Errorf("groupID cannot be empty")
+		}
+		if newLeaf == "" {
+			return fmt.Errorf("newLeaf cannot be empty")
+		}
 
-	resp, err := client.request().
-		SetQueryParam("newext", newLeaf).
-		Put(fmt.Sprintf("/groupMove/%s", regid))
-	if err != nil {
-		return err
-	}
-	if resp.IsError() {
-		return formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return nil
+		// Resolve regid for idempotent move
+		grp, err := client.GetGroupContext(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		regid := grp.Regid
+		if regid == "" {
+			return fmt.Errorf("could not resolve group regid")
+		}
+		// Names of the group and of every group under it may change
+		defer client.cache.invalidate(true)
+
+		resp, err := client.request(ctx).
+			SetQueryParam("newext", newLeaf).
+			SetPathParam("regid", regid).
+			Put("/groupMove/{regid}")
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return newAPIError(resp)
+		}
+		return nil
+	})
 }
 
 // MoveGroup changes only the stem (path prefix) of a group while preserving its terminal (leaf) name.
 // The groupID argument may be a group name or a regid. The method first resolves the
 // group's regid and then performs the move using /groupMove/{regid}?newstem=...
 func (client *Client) MoveGroup(groupID string, newStem string) error {
-	if groupID == "" {
-		return fmt.Errorf("groupID cannot be empty")
-	}
-	if newStem == "" {
-		return fmt.Errorf("newStem cannot be empty")
-	}
+	return client.MoveGroupContext(context.Background(), groupID, newStem)
+}
 
-	// Resolve regid for idempotent move
-	grp, err := client.GetGroup(groupID)
-	if err != nil {
-		return err
-	}
-	regid := grp.Regid
-	if regid == "" {
-		return fmt.Errorf("could not resolve group regid")
-	}
+// MoveGroupContext is like MoveGroup but carries ctx through to the API requests.
+func (client *Client) MoveGroupContext(ctx context.Context, groupID string, newStem string) error {
+	return client.invoke(ctx, "MoveGroup", groupID, func(ctx context.Context) error {
+		if groupID == "" {
+			return fmt.Errorf("groupID cannot be empty")
+		}
+		if newStem == "" {
+			return fmt.Errorf("newStem cannot be empty")
+		}
 
-	resp, err := client.request().
-		SetQueryParam("newstem", newStem).
-		Put(fmt.Sprintf("/groupMove/%s", regid))
-	if err != nil {
-		return err
-	}
-	if resp.IsError() {
-		return formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return nil
+		// Resolve regid for idempotent move
+		grp, err := client.GetGroupContext(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		regid := grp.Regid
+		if regid == "" {
+			return fmt.Errorf("could not resolve group regid")
+		}
+		// Names of the group and of every group under it may change
+		defer client.cache.invalidate(true)
+
+		resp, err := client.request(ctx).
+			SetQueryParam("newstem", newStem).
+			SetPathParam("regid", regid).
+			Put("/groupMove/{regid}")
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return newAPIError(resp)
+		}
+		return nil
+	})
 }
@@ -0,0 +1,209 @@
+package gws_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// newRetryClient returns a client of srv that retries 503s once without waiting long.
+func newRetryClient(t *testing.T, srv *gwstest.Server) *gws.Client {
+	t.Helper()
+	cfg := srv.Config()
+	cfg.Retry = &gws.RetryPolicy{
+		MaxAttempts:       2,
+		InitialBackoff:    time.Millisecond,
+		MaxBackoff:        time.Millisecond,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// countRequests returns how many requests to srv had the given method.
+func countRequests(srv *gwstest.Server, method string) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUpdateGroupRetried(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_test"})
+	client := newRetryClient(t, srv)
+
+	group, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.DisplayName = "Renamed"
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	if _, err := client.UpdateGroup(group); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if n := countRequests(srv, http.MethodPut); n != 2 {
+		t.Errorf("sent %d PUTs, want 2", n)
+	}
+}
+
+// newModifyClients returns a server with u_test, a client of it making at most attempts
+// tries in ModifyGroup, and a second client standing in for another writer.
+func newModifyClients(t *testing.T, attempts int) (srv *gwstest.Server, client, other *gws.Client) {
+	t.Helper()
+	srv = gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddGroup(gws.Group{ID: "u_test", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "joeuser"}}})
+	cfg := srv.Config()
+	cfg.ModifyAttempts = attempts
+	var err error
+	if client, err = gws.NewClient(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if other, err = gws.NewClient(srv.Config()); err != nil {
+		t.Fatal(err)
+	}
+	return srv, client, other
+}
+
+// changeElsewhere makes another writer's change to the group description.
+func changeElsewhere(t *testing.T, other *gws.Client, description string) {
+	t.Helper()
+	if _, err := other.ModifyGroup("u_test", func(g *gws.Group) error {
+		g.Description = description
+		return nil
+	}); err != nil {
+		t.Fatalf("other writer: %v", err)
+	}
+}
+
+func TestModifyGroupReappliesAfterConflict(t *testing.T) {
+	srv, client, other := newModifyClients(t, 0)
+
+	calls := 0
+	group, err := client.ModifyGroup("u_test", func(g *gws.Group) error {
+		calls++
+		if calls == 1 {
+			// Someone else saves between our GET and PUT
+			changeElsewhere(t, other, "changed elsewhere")
+		}
+		g.DisplayName = fmt.Sprintf("Renamed %d", calls)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyGroup: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("mutate called %d times, want 2", calls)
+	}
+	saved, _ := srv.Group("u_test")
+	if saved.DisplayName != "Renamed 2" || saved.Description != "changed elsewhere" {
+		t.Errorf("saved %q, %q; want our second change on top of the other writer's", saved.DisplayName, saved.Description)
+	}
+	if group.DisplayName != saved.DisplayName || group.ETag() == "" {
+		t.Errorf("returned %+v, want the saved group with its ETag", group)
+	}
+}
+
+func TestModifyGroupNoChange(t *testing.T) {
+	srv, client, _ := newModifyClients(t, 0)
+
+	for _, mutate := range []func(*gws.Group) error{
+		func(*gws.Group) error { return nil },
+		func(g *gws.Group) error { g.Admins = slices.Clone(g.Admins); return nil },
+	} {
+		if _, err := client.ModifyGroup("u_test", mutate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countRequests(srv, http.MethodPut); n != 0 {
+		t.Errorf("sent %d PUTs for unchanged groups, want 0", n)
+	}
+
+	failed := errors.New("refused")
+	if _, err := client.ModifyGroup("u_test", func(g *gws.Group) error {
+		g.DisplayName = "Renamed"
+		return failed
+	}); !errors.Is(err, failed) {
+		t.Errorf("ModifyGroup with a failing mutate = %v, want its error", err)
+	}
+	if n := countRequests(srv, http.MethodPut); n != 0 {
+		t.Errorf("sent %d PUTs after mutate failed, want 0", n)
+	}
+}
+
+func TestModifyGroupGivesUp(t *testing.T) {
+	for _, tc := range []struct {
+		attempts, want int
+	}{
+		{2, 2},
+		{0, 5}, // the default
+	} {
+		srv, client, other := newModifyClients(t, tc.attempts)
+		calls := 0
+		_, err := client.ModifyGroup("u_test", func(g *gws.Group) error {
+			calls++
+			changeElsewhere(t, other, fmt.Sprintf("changed elsewhere %d", calls))
+			g.DisplayName = "Renamed"
+			return nil
+		})
+		if !errors.Is(err, gws.ErrPreconditionFailed) {
+			t.Errorf("ModifyAttempts %d: ModifyGroup = %v, want ErrPreconditionFailed", tc.attempts, err)
+		}
+		if calls != tc.want {
+			t.Errorf("ModifyAttempts %d: mutate called %d times, want %d", tc.attempts, calls, tc.want)
+		}
+		if saved, _ := srv.Group("u_test"); saved.DisplayName == "Renamed" {
+			t.Errorf("ModifyAttempts %d: the change was saved", tc.attempts)
+		}
+	}
+}
+
+func TestUpdateGroupWithoutETag(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_test", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "joeuser"}}})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Built by hand rather than read, so it has no ETag
+	group := &gws.Group{ID: "u_test", DisplayName: "Renamed", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "joeuser"}}}
+	if _, err := client.UpdateGroup(group); !errors.Is(err, gws.ErrMissingETag) {
+		t.Fatalf("UpdateGroup without an ETag = %v, want ErrMissingETag", err)
+	}
+	if n := countRequests(srv, http.MethodPut); n != 0 {
+		t.Fatalf("sent %d PUTs for a group without an ETag, want 0", n)
+	}
+
+	updated, err := client.UpdateGroup(group, gws.WithForce())
+	if err != nil {
+		t.Fatalf("UpdateGroup with WithForce: %v", err)
+	}
+	if updated.DisplayName != "Renamed" {
+		t.Errorf("DisplayName = %q, want Renamed", updated.DisplayName)
+	}
+	reqs := srv.Requests()
+	if put := reqs[len(reqs)-1]; put.Method != http.MethodPut || put.Header.Get("If-Match") != "" {
+		t.Errorf("forced update sent %s with If-Match %q, want a PUT without it", put.Method, put.Header.Get("If-Match"))
+	}
+
+	// An explicit ETag stands in for the missing one
+	if _, err := client.UpdateGroup(group, gws.WithIfMatch(`"stale"`)); !errors.Is(err, gws.ErrPreconditionFailed) {
+		t.Errorf("UpdateGroup with a stale WithIfMatch = %v, want ErrPreconditionFailed", err)
+	}
+}
@@ -0,0 +1,158 @@
+package gws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// SearchIter is like DoSearch but decodes the results one at a time as they arrive,
+// so memory use stays flat however many groups match. Iteration stops after the first
+// error, which is yielded with a zero GroupReference.
+func (client *Client) SearchIter(s *SearchParameters) iter.Seq2[GroupReference, error] {
+	return client.SearchIterContext(context.Background(), s)
+}
+
+// SearchIterContext is like SearchIter but carries ctx through to the API request.
+func (client *Client) SearchIterContext(ctx context.Context, s *SearchParameters) iter.Seq2[GroupReference, error] {
+	return streamData[GroupReference](client, ctx, "SearchIter", "", func(ctx context.Context) *resty.Request {
+		return client.request(ctx).SetQueryParamsFromValues(s.parameters)
+	}, "/search")
+}
+
+// MembersIter is like GetMembership but decodes the members one at a time as they arrive.
+// Iteration stops after the first error, which is yielded with a zero Member.
+func (client *Client) MembersIter(groupid string) iter.Seq2[Member, error] {
+	return client.MembersIterContext(context.Background(), groupid)
+}
+
+// MembersIterContext is like MembersIter but carries ctx through to the API request.
+func (client *Client) MembersIterContext(ctx context.Context, groupid string) iter.Seq2[Member, error] {
+	return streamData[Member](client, ctx, "MembersIter", groupid, func(ctx context.Context) *resty.Request {
+		return client.request(ctx).SetPathParam("groupid", groupid)
+	}, "/group/{groupid}/member")
+}
+
+// EffectiveMembersIter is like GetEffectiveMembership but decodes the members one at a time
+// as they arrive, so memory use stays flat for groups with hundreds of thousands of effective
+// members. Iteration stops after the first error, which is yielded with a zero Member.
+func (client *Client) EffectiveMembersIter(groupid string) iter.Seq2[Member, error] {
+	return client.EffectiveMembersIterContext(context.Background(), groupid)
+}
+
+// EffectiveMembersIterContext is like EffectiveMembersIter but carries ctx through to the API request.
+func (client *Client) EffectiveMembersIterContext(ctx context.Context, groupid string) iter.Seq2[Member, error] {
+	return streamData[Member](client, ctx, "EffectiveMembersIter", groupid, func(ctx context.Context) *resty.Request {
+		return client.request(ctx).SetPathParam("groupid", groupid)
+	}, "/group/{groupid}/effective_member")
+}
+
+// streamData returns a sequence that, when ranged over, runs the named operation: a GET for
+// path on a request from newRequest, yielding each element of the "data" array of the response
+// as it is decoded. Breaking out of the loop closes the response body. Middleware sees the
+// whole iteration as the operation, including the time spent in the loop body.
+func streamData[T any](client *Client, ctx context.Context, name, groupid string, newRequest func(context.Context) *resty.Request, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		done := false
+		guarded := func(v T, err error) bool {
+			more := yield(v, err)
+			done = err != nil || !more
+			return more
+		}
+		err := client.invoke(ctx, name, groupid, func(ctx context.Context) error {
+			return decodeData(newRequest(ctx), path, guarded)
+		})
+		if err != nil && !done {
+			// A middleware failed the operation without running it
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// decodeData sends a GET for path and yields each element of the "data" array of the response.
+// An error is yielded and also returned.
+func decodeData[T any](req *resty.Request, path string, yield func(T, error) bool) error {
+	var zero T
+	fail := func(err error) error {
+		yield(zero, err)
+		return err
+	}
+
+	resp, err := req.SetDoNotParseResponse(true).Get(path)
+	if err != nil {
+		return fail(err)
+	}
+	recordStatus(resp)
+	body := resp.RawBody()
+	defer body.Close()
+
+	dec := json.NewDecoder(body)
+	if resp.IsError() {
+		e := newAPIError(resp).(*APIError)
+		var er errorResponse
+		if dec.Decode(&er) == nil {
+			e.withBody(&er)
+		}
+		return fail(e)
+	}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return fail(err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fail(err)
+		}
+		if key, _ := tok.(string); !strings.EqualFold(key, "data") {
+			// Skip schemas, meta and anything else ahead of the data
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return fail(err)
+		}
+		if tok == nil {
+			return nil // "data": null
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return fail(fmt.Errorf("unexpected JSON token %v, expected [", tok))
+		}
+		for n := 1; dec.More(); n++ {
+			var item T
+			if err := dec.Decode(&item); err != nil {
+				return fail(err)
+			}
+			if _, ok := any(item).(Member); ok {
+				setMemberCount(req.Context(), n)
+			}
+			if !yield(item, nil) {
+				return nil
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// expectDelim reads the next token and checks that it is the delimiter want.
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("unexpected JSON token %v, expected %v", tok, want)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package gws_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+func TestEffectiveMembersIterRetryReleasesSlot(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddPeople("joeuser", "janeuser")
+	srv.AddGroup(gws.Group{ID: "u_a"},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "janeuser"})
+
+	cfg := srv.Config()
+	cfg.MaxInFlight = 1
+	cfg.Retry = &gws.RetryPolicy{
+		MaxAttempts:       2,
+		InitialBackoff:    time.Millisecond,
+		MaxBackoff:        time.Millisecond,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.FailNext(1, http.StatusServiceUnavailable)
+
+	// The failed attempt holds the only in-flight slot until its body is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ids []string
+	for m, err := range client.EffectiveMembersIterContext(ctx, "u_a") {
+		if err != nil {
+			t.Fatalf("EffectiveMembersIter: %v", err)
+		}
+		ids = append(ids, m.ID)
+	}
+	if !slices.Equal(ids, []string{"joeuser", "janeuser"}) {
+		t.Errorf("members = %v, want [joeuser janeuser]", ids)
+	}
+}
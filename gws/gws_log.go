@@ -0,0 +1,189 @@
+package gws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// requestIDHeader carries a client generated ID for each request, kept across retries,
+// so a log line can be matched with the service's own logs.
+const requestIDHeader = "X-Request-Id"
+
+// attemptKey is the context key for the attempt number of the current request.
+type attemptKey struct{}
+
+// LogValue renders the Config for log/slog with the client key and any proxy password left out.
+func (c Config) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("api_url", c.APIUrl),
+		slog.Duration("timeout", c.Timeout*time.Second),
+		slog.Bool("synchronized", c.Synchronized),
+		slog.Bool("skip_tls_verify", c.SkipTLSVerify),
+		slog.String("ca_file", c.CAFile),
+		slog.String("client_cert", c.ClientCert),
+		slog.String("pkcs12_file", c.PKCS12File),
+	}
+	if c.Proxy != "" {
+		proxy := c.Proxy
+		if u, err := url.Parse(c.Proxy); err == nil {
+			proxy = u.Redacted()
+		}
+		attrs = append(attrs, slog.String("proxy", proxy))
+	}
+	if c.ClientKey != "" {
+		attrs = append(attrs, slog.String("client_key", "[REDACTED]"))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// installLogger hooks the logger into request setup and retries, and replaces resty's own
+// logger so that nothing is written to stderr.
+func (client *Client) installLogger(logger *slog.Logger) {
+	client.resty.SetLogger(restyLogger{logger})
+	client.resty.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if req.Header.Get(requestIDHeader) == "" {
+			req.SetHeader(requestIDHeader, newRequestID())
+		}
+		req.SetContext(context.WithValue(req.Context(), attemptKey{}, req.Attempt))
+		return nil
+	})
+	client.resty.AddRetryHook(func(resp *resty.Response, err error) {
+		if resp == nil || resp.Request == nil {
+			return
+		}
+		attrs := []any{
+			slog.String("method", resp.Request.Method),
+			slog.String("request_id", resp.Request.Header.Get(requestIDHeader)),
+			slog.Int("attempt", resp.Request.Attempt),
+		}
+		if req := resp.Request.RawRequest; req != nil {
+			path, _ := redactMemberIDs(req.URL.Path)
+			attrs = append(attrs, slog.String("path", path))
+		}
+		if err != nil {
+			// A *url.Error repeats the full URL, member IDs and all
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			attrs = append(attrs, slog.Any("error", err))
+		} else {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode()))
+		}
+		if resp.Request.Attempt > client.resty.RetryCount {
+			// resty runs retry hooks after the last attempt too
+			logger.Log(resp.Request.Context(), slog.LevelWarn, "gws request retries exhausted", attrs...)
+			return
+		}
+		logger.Log(resp.Request.Context(), slog.LevelInfo, "gws request will be retried", attrs...)
+	})
+	logger.Debug("gws client configured", slog.Any("config", client.config))
+}
+
+// restyLogger passes resty's own messages to a slog.Logger. They repeat failures that
+// logTransport has already logged, with the full URL and so any member IDs, so all of
+// them go out at debug whatever level resty gives them.
+type restyLogger struct {
+	logger *slog.Logger
+}
+
+func (l restyLogger) Errorf(format string, v ...any) { l.log("error", format, v...) }
+
+func (l restyLogger) Warnf(format string, v ...any) { l.log("warn", format, v...) }
+
+func (l restyLogger) Debugf(format string, v ...any) { l.log("debug", format, v...) }
+
+func (l restyLogger) log(level, format string, v ...any) {
+	if !l.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	l.logger.Debug(strings.TrimSpace(fmt.Sprintf(format, v...)), slog.String("source", "resty"), slog.String("resty_level", level))
+}
+
+// logTransport logs every HTTP attempt with its method, path, status and duration.
+type logTransport struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+// RoundTrip sends req and logs the outcome. Failures and throttling are logged at warn,
+// other client errors at info and successes at debug. Member ID lists in the path are
+// only logged at debug.
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil:
+		if !errors.Is(err, context.Canceled) {
+			level = slog.LevelWarn
+		}
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		level = slog.LevelWarn
+	case resp.StatusCode >= 400:
+		level = slog.LevelInfo
+	}
+	if !t.logger.Enabled(ctx, level) {
+		return resp, err
+	}
+
+	path := req.URL.Path
+	attrs := []any{
+		slog.String("method", req.Method),
+		slog.String("request_id", req.Header.Get(requestIDHeader)),
+		slog.Duration("duration", elapsed),
+	}
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		attrs = append(attrs, slog.Int("attempt", attempt))
+	}
+	if level > slog.LevelDebug {
+		var n int
+		path, n = redactMemberIDs(path)
+		if n > 0 {
+			attrs = append(attrs, slog.Int("member_count", n))
+		}
+	}
+	attrs = append(attrs, slog.String("path", path))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		t.logger.Log(ctx, level, "gws request failed", attrs...)
+		return resp, err
+	}
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	t.logger.Log(ctx, level, "gws request", attrs...)
+	return resp, err
+}
+
+// redactMemberIDs replaces the member ID list in a membership path with a placeholder
+// and returns the number of IDs it held.
+func redactMemberIDs(path string) (string, int) {
+	segments := strings.Split(path, "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == "member" || segments[i] == "effective_member" {
+			n := len(strings.Split(segments[i+1], ","))
+			segments[i+1] = "{memberids}"
+			return strings.Join(segments, "/"), n
+		}
+	}
+	return path, 0
+}
+
+// newRequestID returns a random ID for a request.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
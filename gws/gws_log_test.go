@@ -0,0 +1,53 @@
+package gws_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+func TestLoggerReplacesRestyLogger(t *testing.T) {
+	// resty's default logger writes to the os.Stderr of the time the client is made
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	srv := gwstest.NewServer()
+	cfg := srv.Config()
+	srv.Close() // every attempt fails to connect
+	var logged bytes.Buffer
+	cfg.Logger = slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	cfg.Retry = &gws.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.IsMember("u_test", "secret-member"); err == nil {
+		t.Fatal("IsMember succeeded against a closed server")
+	}
+	os.Stderr = stderr
+	w.Close()
+	written, _ := io.ReadAll(r)
+
+	if len(written) != 0 {
+		t.Errorf("wrote to stderr: %s", written)
+	}
+	if !strings.Contains(logged.String(), "gws request failed") {
+		t.Errorf("failure not logged:\n%s", logged.String())
+	}
+	if strings.Contains(logged.String(), "secret-member") {
+		t.Errorf("member ID logged above debug:\n%s", logged.String())
+	}
+}
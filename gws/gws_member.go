@@ -14,10 +14,10 @@ type Member struct {
 	// ID of member
 	ID string `json:"id"`
 
-	// Type of member enum [ direct, indirect ]
+	// Type of member enum [ direct, indirect ], set by ExpandMembership
 	MType string `json:"-"`
 
-	// Source group(s) if not direct member
+	// Source group(s) if not direct member, set by ExpandMembership
 	Source string `json:"-"`
 }
 
@@ -74,14 +74,12 @@ func (members *MemberList) Match(memberType MemberType) *MemberList {
 	return &newList
 }
 
-// Contains returns true if the MemberList contains the given Member ID
+// Contains returns true if the MemberList contains the given Member ID.
+// Long lists are indexed the first time they are checked, so later calls on the same list
+// take constant time. The index follows appends, removals and reordering; after assigning
+// a different member to an element in place, use a copy of the list or build an Index.
 func (ml MemberList) Contains(id string) bool {
-	for _, m := range ml {
-		if m.ID == id {
-			return true
-		}
-	}
-	return false
+	return memberIndexes.contains(ml, id)
 }
 
 // Functions to manipulate and set full MemberLists via SetMembership()
@@ -131,8 +129,9 @@ func inferredMType(id string) MemberType {
 
 // AddMemberByID modifies a MemberList, inferring the MemberType if each id and appending Members
 func (ml *MemberList) AppendMemberByID(id ...string) (*MemberList, error) {
+	have := ml.Index()
 	for _, idStr := range id {
-		if ml.Contains(idStr) {
+		if have.Contains(idStr) {
 			continue
 		}
 		mType := inferredMType(idStr)
@@ -141,20 +140,14 @@ func (ml *MemberList) AppendMemberByID(id ...string) (*MemberList, error) {
 			return ml, fmt.Errorf("Member type could not be inferred for ID: %s", idStr)
 		}
 		*ml = append(*ml, Member{Type: mType, ID: idStr})
+		have.add(setKey{typ: string(mType), id: idStr})
 	}
 	return ml, nil
 }
 
 // DeleteMemberByID modifies a MemberList, removing supplied ID strings from Members.
 func (ml *MemberList) RemoveMemberByID(id ...string) (*MemberList, error) {
-	for _, idStr := range id {
-		for i, m := range *ml {
-			if m.ID == idStr {
-				*ml = append((*ml)[:i], (*ml)[i+1:]...)
-				break
-			}
-		}
-	}
+	*ml = removeIDs(*ml, id)
 	return ml, nil
 }
 
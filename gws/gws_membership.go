@@ -1,8 +1,8 @@
 package gws
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"slices"
 )
 
 // membershipMeta is metadata returned by membership API requests.
@@ -74,183 +74,370 @@ type putMembership struct {
 
 // GetMembership returns membership of the group specified by the groupid.
 func (client *Client) GetMembership(groupid string) (*MemberList, error) {
+	return client.GetMembershipContext(context.Background(), groupid)
+}
 
-	resp, err := client.request().
-		SetResult(membershipResponse{}).
-		Get(fmt.Sprintf("/group/%s/member", groupid))
-	if err != nil {
-		return &MemberList{}, err // make(MemberList, 0), err
-	}
-	if resp.IsError() {
-		return &MemberList{}, formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return &resp.Result().(*membershipResponse).Members, nil
+// GetMembershipContext is like GetMembership but carries ctx through to the API request.
+func (client *Client) GetMembershipContext(ctx context.Context, groupid string) (*MemberList, error) {
+	return invokeResult(client, ctx, "GetMembership", groupid, func(ctx context.Context) (*MemberList, error) {
+		key := cacheKey{kind: cacheMembers, group: groupid}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			return cachedMembers(ctx, cached)
+		}
+
+		resp, err := client.request(ctx).
+			SetResult(membershipResponse{}).
+			SetPathParam("groupid", groupid).
+			Get("/group/{groupid}/member")
+		if err != nil {
+			return &MemberList{}, err // make(MemberList, 0), err
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return &MemberList{}, err
+		}
+		members := &resp.Result().(*membershipResponse).Members
+		setMemberCount(ctx, len(*members))
+		client.cache.put(key, gen, slices.Clone(*members), nil)
+		return members, nil
+	})
 }
 
 // GetEffectiveMembership returns membership of the group referenced by the groupid.
 func (client *Client) GetEffectiveMembership(groupid string) (*MemberList, error) {
+	return client.GetEffectiveMembershipContext(context.Background(), groupid)
+}
 
-	resp, err := client.request().
-		SetResult(effMembershipResponse{}).
-		Get(fmt.Sprintf("/group/%s/effective_member", groupid))
-	if err != nil {
-		return &MemberList{}, err //make(MemberList, 0), err
-	}
-	if resp.IsError() {
-		return &MemberList{}, formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return &resp.Result().(*effMembershipResponse).Members, nil
+// GetEffectiveMembershipContext is like GetEffectiveMembership but carries ctx through to the API request.
+func (client *Client) GetEffectiveMembershipContext(ctx context.Context, groupid string) (*MemberList, error) {
+	return invokeResult(client, ctx, "GetEffectiveMembership", groupid, func(ctx context.Context) (*MemberList, error) {
+		key := cacheKey{kind: cacheEffectiveMembers, group: groupid}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			return cachedMembers(ctx, cached)
+		}
+
+		resp, err := client.request(ctx).
+			SetResult(effMembershipResponse{}).
+			SetPathParam("groupid", groupid).
+			Get("/group/{groupid}/effective_member")
+		if err != nil {
+			return &MemberList{}, err //make(MemberList, 0), err
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return &MemberList{}, err
+		}
+		members := &resp.Result().(*effMembershipResponse).Members
+		setMemberCount(ctx, len(*members))
+		client.cache.put(key, gen, slices.Clone(*members), nil)
+		return members, nil
+	})
 }
 
 // GetMember returns one member of the group, if present.
 func (client *Client) GetMember(groupid string, id string) (*Member, error) {
-	resp, err := client.request().
-		SetResult(membershipResponse{}).
-		Get(fmt.Sprintf("/group/%s/member/%s", groupid, id))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+	return client.GetMemberContext(context.Background(), groupid, id)
+}
 
-	m := resp.Result().(*membershipResponse).Members[0]
-	return &m, nil
+// GetMemberContext is like GetMember but carries ctx through to the API request.
+func (client *Client) GetMemberContext(ctx context.Context, groupid string, id string) (*Member, error) {
+	return invokeResult(client, ctx, "GetMember", groupid, func(ctx context.Context) (*Member, error) {
+		key := cacheKey{kind: cacheMember, group: groupid, member: id}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			return cachedMember(cached)
+		}
+
+		resp, err := client.request(ctx).
+			SetResult(membershipResponse{}).
+			SetPathParam("groupid", groupid).
+			SetPathParam("memberid", id).
+			Get("/group/{groupid}/member/{memberid}")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+
+		members := resp.Result().(*membershipResponse).Members
+		if len(members) == 0 {
+			err := memberNotFoundError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+		m := members[0]
+		client.cache.put(key, gen, &m, nil)
+		return &m, nil
+	})
 }
 
 // GetEffectiveMember returns one effective member of the group, if present.
 func (client *Client) GetEffectiveMember(groupid string, id string) (*Member, error) {
-	resp, err := client.request().
-		SetResult(membershipResponse{}).
-		Get(fmt.Sprintf("/group/%s/effective_member/%s", groupid, id))
-	if err != nil {
-		//return Member{}, err
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+	return client.GetEffectiveMemberContext(context.Background(), groupid, id)
+}
 
-	m := resp.Result().(*membershipResponse).Members[0]
-	return &m, nil
+// GetEffectiveMemberContext is like GetEffectiveMember but carries ctx through to the API request.
+func (client *Client) GetEffectiveMemberContext(ctx context.Context, groupid string, id string) (*Member, error) {
+	return invokeResult(client, ctx, "GetEffectiveMember", groupid, func(ctx context.Context) (*Member, error) {
+		key := cacheKey{kind: cacheEffectiveMember, group: groupid, member: id}
+		cached, fresh, gen := client.cache.get(key)
+		if fresh {
+			return cachedMember(cached)
+		}
+
+		resp, err := client.request(ctx).
+			SetResult(membershipResponse{}).
+			SetPathParam("groupid", groupid).
+			SetPathParam("memberid", id).
+			Get("/group/{groupid}/effective_member/{memberid}")
+		if err != nil {
+			//return Member{}, err
+			return nil, err
+		}
+		if resp.IsError() {
+			err := newAPIError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+
+		members := resp.Result().(*membershipResponse).Members
+		if len(members) == 0 {
+			err := memberNotFoundError(resp)
+			client.cache.put(key, gen, nil, err)
+			return nil, err
+		}
+		m := members[0]
+		client.cache.put(key, gen, &m, nil)
+		return &m, nil
+	})
 }
 
 // IsMember indicates true if groupid exists and id is member.
-// Group not found, member not found or general error all return false.
+// Member not found returns false with a nil error. Group not found, permission
+// failures and transport errors are returned as errors. A 404 that does not say what
+// is missing is settled by reading the group.
 func (client *Client) IsMember(groupid string, id string) (bool, error) {
-	member, _ := client.GetMember(groupid, id)
-	if member == nil || member.ID == "" {
+	return client.IsMemberContext(context.Background(), groupid, id)
+}
+
+// IsMemberContext is like IsMember but carries ctx through to the API requests.
+func (client *Client) IsMemberContext(ctx context.Context, groupid string, id string) (bool, error) {
+	return invokeResult(client, ctx, "IsMember", groupid, func(ctx context.Context) (bool, error) {
+		member, err := client.GetMemberContext(withHandled(ctx, isMaybeMemberNotFound), groupid, id)
+		if err != nil {
+			return client.memberAbsent(ctx, groupid, err)
+		}
+		return member.ID != "", nil
+	})
+}
+
+// memberAbsent turns the error of a member lookup in groupid into the answer of IsMember
+// or IsEffectiveMember: false for a 404 about the member, and for a 404 that does not say
+// what is missing if the group itself can be read.
+func (client *Client) memberAbsent(ctx context.Context, groupid string, err error) (bool, error) {
+	switch {
+	case isMemberNotFound(err):
+		return false, nil
+	case isMaybeMemberNotFound(err):
+		if _, gerr := client.GetGroupContext(ctx, groupid); gerr != nil {
+			return false, gerr
+		}
 		return false, nil
 	}
-	return true, nil
+	return false, err
 }
 
 // IsEffectiveMember indicates true if groupid exists and id is effective member.
-// Group not found, member not found or general error all return false.
+// Member not found returns false with a nil error. Group not found, permission
+// failures and transport errors are returned as errors.
 func (client *Client) IsEffectiveMember(groupid string, id string) (bool, error) {
-	member, _ := client.GetEffectiveMember(groupid, id)
-	if member == nil || member.ID == "" {
-		return false, nil
-	}
-	return true, nil
+	return client.IsEffectiveMemberContext(context.Background(), groupid, id)
+}
+
+// IsEffectiveMemberContext is like IsEffectiveMember but carries ctx through to the API requests.
+func (client *Client) IsEffectiveMemberContext(ctx context.Context, groupid string, id string) (bool, error) {
+	return invokeResult(client, ctx, "IsEffectiveMember", groupid, func(ctx context.Context) (bool, error) {
+		member, err := client.GetEffectiveMemberContext(withHandled(ctx, isMaybeMemberNotFound), groupid, id)
+		if err != nil {
+			return client.memberAbsent(ctx, groupid, err)
+		}
+		return member.ID != "", nil
+	})
 }
 
 // MemberCount returns membership count of the group referenced by the groupid.
 // Group not found or general error returns a count of zero.
 func (client *Client) MemberCount(groupid string) (int, error) {
-	resp, err := client.request().
-		SetResult(membershipCountResponse{}).
-		Get(fmt.Sprintf("/group/%s/member?view=count", groupid))
-	if err != nil {
-		return 0, err
-	}
-	if resp.IsError() {
-		return 0, formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return resp.Result().(*membershipCountResponse).Data.Count, nil
+	return client.MemberCountContext(context.Background(), groupid)
+}
+
+// MemberCountContext is like MemberCount but carries ctx through to the API request.
+func (client *Client) MemberCountContext(ctx context.Context, groupid string) (int, error) {
+	return invokeResult(client, ctx, "MemberCount", groupid, func(ctx context.Context) (int, error) {
+		resp, err := client.request(ctx).
+			SetResult(membershipCountResponse{}).
+			SetPathParam("groupid", groupid).
+			SetQueryParam("view", "count").
+			Get("/group/{groupid}/member")
+		if err != nil {
+			return 0, err
+		}
+		if resp.IsError() {
+			return 0, newAPIError(resp)
+		}
+		count := resp.Result().(*membershipCountResponse).Data.Count
+		setMemberCount(ctx, count)
+		return count, nil
+	})
 }
 
 // EffectiveMemberCount returns membership count of the group referenced by the groupid.
 // Group not found or general error returns a count of zero.
 func (client *Client) EffectiveMemberCount(groupid string) (int, error) {
-	resp, err := client.request().
-		SetResult(membershipCountResponse{}).
-		Get(fmt.Sprintf("/group/%s/effective_member?view=count", groupid))
-	if err != nil {
-		return 0, err
-	}
-	if resp.IsError() {
-		return 0, formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return resp.Result().(*membershipCountResponse).Data.Count, nil
+	return client.EffectiveMemberCountContext(context.Background(), groupid)
+}
+
+// EffectiveMemberCountContext is like EffectiveMemberCount but carries ctx through to the API request.
+func (client *Client) EffectiveMemberCountContext(ctx context.Context, groupid string) (int, error) {
+	return invokeResult(client, ctx, "EffectiveMemberCount", groupid, func(ctx context.Context) (int, error) {
+		resp, err := client.request(ctx).
+			SetResult(membershipCountResponse{}).
+			SetPathParam("groupid", groupid).
+			SetQueryParam("view", "count").
+			Get("/group/{groupid}/effective_member")
+		if err != nil {
+			return 0, err
+		}
+		if resp.IsError() {
+			return 0, newAPIError(resp)
+		}
+		count := resp.Result().(*membershipCountResponse).Data.Count
+		setMemberCount(ctx, count)
+		return count, nil
+	})
 }
 
 // AddMembers adds one or more member IDs to the referenced group and returns an array of memberIDs that do not exist and could not be added.
+// Long ID lists are split into several requests, see MemberChunkError.
 func (client *Client) AddMembers(groupid string, memberIDs ...string) ([]string, error) {
-	resp, err := client.request().
-		SetQueryString(client.syncQueryString()).
-		SetResult(errorResponse{}).
-		Put(fmt.Sprintf("/group/%s/member/%s", groupid, strings.Join(memberIDs, ",")))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+	return client.AddMembersContext(context.Background(), groupid, memberIDs)
+}
 
-	// PUT member is weird, returns "error" on 200
-	er := resp.Result().(*errorResponse)
-	return er.Errors[0].NotFound, nil
+// AddMembersContext is like AddMembers but carries ctx through to the API requests and
+// accepts per-request options.
+func (client *Client) AddMembersContext(ctx context.Context, groupid string, memberIDs []string, opts ...RequestOption) ([]string, error) {
+	return invokeResult(client, ctx, "AddMembers", groupid, func(ctx context.Context) ([]string, error) {
+		defer client.cache.invalidate(true, groupid)
+		setMemberCount(ctx, len(memberIDs))
+		return client.forMemberChunks(ctx, memberIDs, func(ctx context.Context, chunk []string) ([]string, error) {
+			resp, err := client.writeRequest(ctx, opts).
+				SetResult(errorResponse{}).
+				SetPathParam("groupid", groupid).
+				SetRawPathParam("memberids", escapeMemberIDs(chunk)).
+				Put("/group/{groupid}/member/{memberids}")
+			if err != nil {
+				return nil, err
+			}
+			if resp.IsError() {
+				return nil, newAPIError(resp)
+			}
+
+			// PUT member is weird, returns "error" on 200
+			er := resp.Result().(*errorResponse)
+			return er.notFound(), nil
+		})
+	})
 }
 
 // DeleteMembers removes one or more member IDs from the referenced group.
+// Long ID lists are split into several requests, see MemberChunkError.
 func (client *Client) DeleteMembers(groupid string, memberIDs ...string) error {
-	resp, err := client.request().
-		SetQueryString(client.syncQueryString()).
-		Delete(fmt.Sprintf("/group/%s/member/%s", groupid, strings.Join(memberIDs, ",")))
-	if err != nil {
+	return client.DeleteMembersContext(context.Background(), groupid, memberIDs)
+}
+
+// DeleteMembersContext is like DeleteMembers but carries ctx through to the API requests and
+// accepts per-request options.
+func (client *Client) DeleteMembersContext(ctx context.Context, groupid string, memberIDs []string, opts ...RequestOption) error {
+	return client.invoke(ctx, "DeleteMembers", groupid, func(ctx context.Context) error {
+		defer client.cache.invalidate(true, groupid)
+		setMemberCount(ctx, len(memberIDs))
+		_, err := client.forMemberChunks(ctx, memberIDs, func(ctx context.Context, chunk []string) ([]string, error) {
+			resp, err := client.writeRequest(ctx, opts).
+				SetPathParam("groupid", groupid).
+				SetRawPathParam("memberids", escapeMemberIDs(chunk)).
+				Delete("/group/{groupid}/member/{memberids}")
+			if err != nil {
+				return nil, err
+			}
+			if resp.IsError() {
+				return nil, newAPIError(resp)
+			}
+			return nil, nil
+		})
 		return err
-	}
-	if resp.IsError() {
-		return formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return nil
+	})
 }
 
 // SetMembership completely replaces group membership with specified MemberList and returns an array of memberIDs that do not exist and could not be added.
-func (client *Client) SetMembership(groupid string, newMembers *MemberList) ([]string, error) {
-	body := &putMembership{Members: *newMembers}
-
-	resp, err := client.request().
-		SetQueryString(client.syncQueryString()).
-		SetBody(body).
-		SetResult(errorResponse{}).
-		Put(fmt.Sprintf("/group/%s/member", groupid))
-	if err != nil {
-		return nil, err
-	}
-	if resp.IsError() {
-		return nil, formatErrorResponse(resp.Error().(*errorResponse))
-	}
+func (client *Client) SetMembership(groupid string, newMembers *MemberList, opts ...RequestOption) ([]string, error) {
+	return client.SetMembershipContext(context.Background(), groupid, newMembers, opts...)
+}
 
-	// PUT member is weird, returns "error" on 200
-	okError := resp.Result().(*errorResponse)
-	return okError.Errors[0].NotFound, nil
+// SetMembershipContext is like SetMembership but carries ctx through to the API request.
+func (client *Client) SetMembershipContext(ctx context.Context, groupid string, newMembers *MemberList, opts ...RequestOption) ([]string, error) {
+	return invokeResult(client, ctx, "SetMembership", groupid, func(ctx context.Context) ([]string, error) {
+		defer client.cache.invalidate(true, groupid)
+		body := &putMembership{Members: *newMembers}
+		setMemberCount(ctx, len(*newMembers))
+
+		// Full replacement is idempotent, so it is safe to retry
+		resp, err := client.writeRequest(withRetrySafe(ctx), opts).
+			SetBody(body).
+			SetResult(errorResponse{}).
+			SetPathParam("groupid", groupid).
+			Put("/group/{groupid}/member")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, newAPIError(resp)
+		}
+
+		// PUT member is weird, returns "error" on 200
+		okError := resp.Result().(*errorResponse)
+		return okError.notFound(), nil
+	})
 }
 
 // DeleteAllMembers removes all members from the referenced group.
-func (client *Client) DeleteAllMembers(groupid string) error {
-	body := &putMembership{Members: make(MemberList, 0)}
-
-	resp, err := client.request().
-		SetQueryString(client.syncQueryString()).
-		SetBody(body).
-		Put(fmt.Sprintf("/group/%s/member", groupid))
-	if err != nil {
-		return err
-	}
-	if resp.IsError() {
-		return formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return nil
+func (client *Client) DeleteAllMembers(groupid string, opts ...RequestOption) error {
+	return client.DeleteAllMembersContext(context.Background(), groupid, opts...)
+}
+
+// DeleteAllMembersContext is like DeleteAllMembers but carries ctx through to the API request.
+func (client *Client) DeleteAllMembersContext(ctx context.Context, groupid string, opts ...RequestOption) error {
+	return client.invoke(ctx, "DeleteAllMembers", groupid, func(ctx context.Context) error {
+		defer client.cache.invalidate(true, groupid)
+		body := &putMembership{Members: make(MemberList, 0)}
+
+		// Full replacement is idempotent, so it is safe to retry
+		resp, err := client.writeRequest(withRetrySafe(ctx), opts).
+			SetBody(body).
+			SetPathParam("groupid", groupid).
+			Put("/group/{groupid}/member")
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return newAPIError(resp)
+		}
+		return nil
+	})
 }
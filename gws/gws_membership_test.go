@@ -0,0 +1,219 @@
+package gws_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+func TestMemberIDEscaping(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		typ     gws.MemberType
+		id      string
+		escaped string // the ID as it should appear in the request path
+	}{
+		{"uwnetid", gws.MemberTypeUWNetID, "joe_user-2", "joe_user-2"},
+		{"uwnetid reserved characters", gws.MemberTypeUWNetID, "a/b?c#d%e f", "a%2Fb%3Fc%23d%25e%20f"},
+		{"eppn", gws.MemberTypeEPPN, "joe+test@example.edu", "joe+test@example.edu"},
+		{"uwwi", gws.MemberTypeUWWI, "svc-acct$", "svc-acct$"},
+		{"dns", gws.MemberTypeDNS, "host-1.cac.washington.edu", "host-1.cac.washington.edu"},
+		{"group", gws.MemberTypeGroup, "u_stem_sub-group", "u_stem_sub-group"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := gwstest.NewServer()
+			defer srv.Close()
+			srv.AddGroup(gws.Group{ID: "u_test"})
+			switch tc.typ {
+			case gws.MemberTypeUWNetID:
+				srv.AddPeople(tc.id)
+			case gws.MemberTypeGroup:
+				srv.AddGroup(gws.Group{ID: tc.id})
+			}
+			client, err := gws.NewClient(srv.Config())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if notFound, err := client.AddMembers("u_test", tc.id); err != nil || len(notFound) != 0 {
+				t.Fatalf("AddMembers = %v, %v; want no not found IDs", notFound, err)
+			}
+			if got := srv.Members("u_test"); len(got) != 1 || got[0] != (gws.Member{Type: tc.typ, ID: tc.id}) {
+				t.Errorf("members = %v, want one %s %q", got, tc.typ, tc.id)
+			}
+			if ok, err := client.IsMember("u_test", tc.id); err != nil || !ok {
+				t.Errorf("IsMember = %v, %v; want true", ok, err)
+			}
+			// Nested groups are expanded into their members, so they are not effective members
+			if ok, err := client.IsEffectiveMember("u_test", tc.id); err != nil || ok != (tc.typ != gws.MemberTypeGroup) {
+				t.Errorf("IsEffectiveMember = %v, %v", ok, err)
+			}
+			if err := client.DeleteMembers("u_test", tc.id); err != nil {
+				t.Fatalf("DeleteMembers: %v", err)
+			}
+			if got := srv.Members("u_test"); len(got) != 0 {
+				t.Errorf("members after delete = %v, want none", got)
+			}
+
+			var got []gwstest.Request
+			for _, r := range srv.Requests() {
+				got = append(got, gwstest.Request{Method: r.Method, Path: r.Path, Query: r.Query})
+			}
+			want := []gwstest.Request{
+				{Method: "PUT", Path: "/group/u_test/member/" + tc.escaped},
+				{Method: "GET", Path: "/group/u_test/member/" + tc.escaped},
+				{Method: "GET", Path: "/group/u_test/effective_member/" + tc.escaped},
+				{Method: "DELETE", Path: "/group/u_test/member/" + tc.escaped},
+			}
+			if !slices.EqualFunc(got, want, func(a, b gwstest.Request) bool {
+				return a.Method == b.Method && a.Path == b.Path && a.Query == b.Query
+			}) {
+				t.Errorf("requests = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestIsMemberWithBare404(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddPeople("joeuser")
+	srv.AddGroup(gws.Group{ID: "u_test"}, gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"})
+
+	// A 404 without detail text does not say whether the member or the group is missing
+	cfg := srv.Config()
+	cfg.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil && resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			body := `{"errors":[{"status":404}]}`
+			resp.Body = io.NopCloser(strings.NewReader(body))
+			resp.ContentLength = int64(len(body))
+		}
+		return resp, err
+	})
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		group, id string
+		want      bool
+		wantErr   error
+	}{
+		{"u_test", "joeuser", true, nil},
+		{"u_test", "nobody", false, nil},
+		{"u_missing", "joeuser", false, gws.ErrNotFound},
+	} {
+		ok, err := client.IsMember(tc.group, tc.id)
+		if ok != tc.want || !errors.Is(err, tc.wantErr) {
+			t.Errorf("IsMember(%s, %s) = %v, %v; want %v, %v", tc.group, tc.id, ok, err, tc.want, tc.wantErr)
+		}
+		ok, err = client.IsEffectiveMember(tc.group, tc.id)
+		if ok != tc.want || !errors.Is(err, tc.wantErr) {
+			t.Errorf("IsEffectiveMember(%s, %s) = %v, %v; want %v, %v", tc.group, tc.id, ok, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestAddMembersWithIfMatchNotRetried(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddPeople("joeuser")
+	srv.AddGroup(gws.Group{ID: "u_test"})
+	client := newRetryClient(t, srv)
+
+	srv.FailNext(1, http.StatusServiceUnavailable)
+	if _, err := client.AddMembersContext(context.Background(), "u_test", []string{"joeuser"}, gws.WithIfMatch(`"etag"`)); err == nil {
+		t.Fatal("AddMembers succeeded despite the 503")
+	}
+	if n := countRequests(srv, http.MethodPut); n != 1 {
+		t.Errorf("sent %d PUTs, want 1", n)
+	}
+}
+
+// chunkIDs returns n member IDs, alternating between people known to srv and unknown IDs.
+func chunkIDs(srv *gwstest.Server, n int) (ids, people, unknown []string) {
+	for i := range n {
+		if i%2 == 0 {
+			people = append(people, fmt.Sprintf("user%04d", i))
+			ids = append(ids, people[len(people)-1])
+		} else {
+			unknown = append(unknown, fmt.Sprintf("nobody%04d", i))
+			ids = append(ids, unknown[len(unknown)-1])
+		}
+	}
+	srv.AddPeople(people...)
+	return ids, people, unknown
+}
+
+func TestAddMembersInChunks(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			srv := gwstest.NewServer()
+			defer srv.Close()
+			srv.AddGroup(gws.Group{ID: "u_test"})
+			ids, people, unknown := chunkIDs(srv, 1000)
+			cfg := srv.Config()
+			cfg.MemberChunkConcurrency = concurrency
+			client, err := gws.NewClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			notFound, err := client.AddMembers("u_test", ids...)
+			if err != nil {
+				t.Fatalf("AddMembers: %v", err)
+			}
+			if n := countRequests(srv, http.MethodPut); n < 2 {
+				t.Errorf("sent %d PUTs, want the IDs split over several", n)
+			}
+			if !slices.Equal(notFound, unknown) {
+				t.Errorf("AddMembers not found %d IDs, want the %d unknown IDs in order", len(notFound), len(unknown))
+			}
+			got := srv.Members("u_test").ToIDs()
+			slices.Sort(got)
+			if !slices.Equal(got, people) {
+				t.Errorf("group has %d members, want the %d known people", len(got), len(people))
+			}
+		})
+	}
+}
+
+func TestDeleteMembersChunkFailure(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	_, people, _ := chunkIDs(srv, 1000)
+	var members []gws.Member
+	for _, id := range people {
+		members = append(members, gws.Member{Type: gws.MemberTypeUWNetID, ID: id})
+	}
+	srv.AddGroup(gws.Group{ID: "u_test"}, members...)
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.FailNext(1, http.StatusConflict)
+	err = client.DeleteMembers("u_test", people...)
+	var chunkErr *gws.MemberChunkError
+	if !errors.As(err, &chunkErr) || !errors.Is(err, gws.ErrConflict) {
+		t.Fatalf("DeleteMembers error = %v, want a MemberChunkError wrapping ErrConflict", err)
+	}
+	if chunkErr.MemberIDs[0] != people[0] || len(chunkErr.MemberIDs) == len(people) {
+		t.Errorf("failed chunk has %d IDs starting at %s, want the first chunk", len(chunkErr.MemberIDs), chunkErr.MemberIDs[0])
+	}
+	// The other chunks still went through
+	if got := srv.Members("u_test").ToIDs(); !slices.Equal(got, chunkErr.MemberIDs) {
+		t.Errorf("group kept %d members, want the %d of the failed chunk", len(got), len(chunkErr.MemberIDs))
+	}
+}
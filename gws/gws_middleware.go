@@ -0,0 +1,170 @@
+package gws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Operation describes one logical client operation, such as GetGroup or SetMembership,
+// as it passes through middleware. An operation may send several HTTP requests, or none
+// if a middleware short-circuits it.
+type Operation struct {
+	// Name is the client method, without any Context suffix
+	Name string
+
+	// GroupID is the group the operation acts on, empty for searches
+	GroupID string
+
+	// Header is sent with every request of the operation. Middleware may add to it
+	// before calling the next handler. A step starts with a copy of its parent's Header.
+	Header http.Header
+
+	// StatusCode is the HTTP status of the last response received by the operation or
+	// any of its steps, zero if none was
+	StatusCode int
+
+	// MemberCount is the number of members sent or received by a membership operation,
+	// or by the last membership step of an operation made of steps
+	MemberCount int
+
+	// Parent is the enclosing operation when this one is a step of another, such as the
+	// GetGroup inside RenameGroup
+	Parent *Operation
+
+	// handled is set on steps whose parent turns some errors into a result
+	handled func(error) bool
+
+	// mu guards StatusCode and MemberCount of the root operation and all its steps, which
+	// requests sent at once, such as member chunks or the groups of a nesting analysis, may
+	// set together
+	mu *sync.Mutex
+}
+
+// ErrorHandled reports whether err, returned by op, is an answer its parent operation
+// expects rather than a failure, such as the member 404 from the GetMember inside IsMember.
+// Middleware that counts failures can leave these out.
+func (op *Operation) ErrorHandled(err error) bool {
+	return err != nil && op.handled != nil && op.handled(err)
+}
+
+// Handler performs an operation. The error it returns is the outcome of the operation.
+type Handler func(ctx context.Context, op *Operation) error
+
+// Middleware wraps every client operation. Wrap returns a handler that may inspect or
+// change op and ctx, call next, and inspect or replace the error next returns.
+type Middleware interface {
+	Wrap(next Handler) Handler
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc func(next Handler) Handler
+
+// Wrap calls f(next).
+func (f MiddlewareFunc) Wrap(next Handler) Handler { return f(next) }
+
+// operationKey is the context key for the current *Operation.
+type operationKey struct{}
+
+// handledKey is the context key for the errors the next step's parent handles.
+type handledKey struct{}
+
+// withHandled returns a context for a step whose parent turns the errors matched by
+// handled into a result.
+func withHandled(ctx context.Context, handled func(error) bool) context.Context {
+	return context.WithValue(ctx, handledKey{}, handled)
+}
+
+// Use appends middleware to the client, after any from Config.Middleware. The first
+// middleware registered is the outermost. It is safe to call Use while the client is in use.
+func (client *Client) Use(mw ...Middleware) {
+	client.mwMu.Lock()
+	defer client.mwMu.Unlock()
+	client.middleware = append(client.middleware[:len(client.middleware):len(client.middleware)], mw...)
+}
+
+// invoke runs fn as the named operation through the middleware chain.
+func (client *Client) invoke(ctx context.Context, name, groupid string, fn func(context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client.mwMu.RLock()
+	chain := client.middleware
+	client.mwMu.RUnlock()
+	if len(chain) == 0 {
+		return fn(ctx)
+	}
+
+	parent, _ := ctx.Value(operationKey{}).(*Operation)
+	op := &Operation{Name: name, GroupID: groupid, Header: make(http.Header), Parent: parent, mu: new(sync.Mutex)}
+	if parent != nil {
+		op.Header = parent.Header.Clone()
+		op.mu = parent.mu
+	}
+	op.handled, _ = ctx.Value(handledKey{}).(func(error) bool)
+	h := func(ctx context.Context, op *Operation) error {
+		ctx = context.WithValue(ctx, operationKey{}, op)
+		if op.handled != nil {
+			// Only the step itself, not its own steps
+			ctx = context.WithValue(ctx, handledKey{}, nil)
+		}
+		return fn(ctx)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i].Wrap(h)
+	}
+	return h(ctx, op)
+}
+
+// invokeResult is like invoke for operations that also return a value.
+func invokeResult[T any](client *Client, ctx context.Context, name, groupid string, fn func(context.Context) (T, error)) (T, error) {
+	var result T
+	err := client.invoke(ctx, name, groupid, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// applyOperation adds the headers of the operation carried by ctx, if any, to req.
+func applyOperation(ctx context.Context, req *resty.Request) {
+	if op, ok := ctx.Value(operationKey{}).(*Operation); ok {
+		for k, v := range op.Header {
+			req.Header[k] = append([]string(nil), v...)
+		}
+	}
+}
+
+// recordStatus stores the status of resp on the operation that sent it and on the
+// operations enclosing it.
+func recordStatus(resp *resty.Response) {
+	if resp == nil || resp.Request == nil {
+		return
+	}
+	op, ok := resp.Request.Context().Value(operationKey{}).(*Operation)
+	if !ok {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	for ; op != nil; op = op.Parent {
+		op.StatusCode = resp.StatusCode()
+	}
+}
+
+// setMemberCount stores n as the member count of the operation carried by ctx, if any,
+// and of the operations enclosing it.
+func setMemberCount(ctx context.Context, n int) {
+	op, ok := ctx.Value(operationKey{}).(*Operation)
+	if !ok {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	for ; op != nil; op = op.Parent {
+		op.MemberCount = n
+	}
+}
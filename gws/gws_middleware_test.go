@@ -0,0 +1,163 @@
+package gws_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// opRecorder is a middleware that adds a header to top level operations and keeps a copy
+// of each operation as it finishes, with its error.
+type opRecorder struct {
+	ops  []gws.Operation
+	errs []error
+}
+
+func (r *opRecorder) Wrap(next gws.Handler) gws.Handler {
+	return func(ctx context.Context, op *gws.Operation) error {
+		if op.Parent == nil {
+			op.Header.Set("X-Caller", "test")
+		}
+		err := next(ctx, op)
+		r.ops = append(r.ops, *op)
+		r.errs = append(r.errs, err)
+		return err
+	}
+}
+
+// last returns the last finished operation with the given name.
+func (r *opRecorder) last(name string) gws.Operation {
+	for i := len(r.ops) - 1; i >= 0; i-- {
+		if r.ops[i].Name == name {
+			return r.ops[i]
+		}
+	}
+	return gws.Operation{}
+}
+
+func newMiddlewareClient(t *testing.T) (*gwstest.Server, *gws.Client, *opRecorder) {
+	t.Helper()
+	srv := gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPeople("joeuser", "janeuser")
+	srv.AddGroup(gws.Group{ID: "u_test"}, gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"})
+	rec := &opRecorder{}
+	cfg := srv.Config()
+	cfg.Middleware = []gws.Middleware{rec}
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client, rec
+}
+
+func TestMiddlewareStepsInheritHeader(t *testing.T) {
+	srv, client, _ := newMiddlewareClient(t)
+
+	if _, err := client.ModifyGroup("u_test", func(g *gws.Group) error {
+		g.DisplayName = "Renamed"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("sent %d requests, want a GET and a PUT", len(reqs))
+	}
+	for _, req := range reqs {
+		if got := req.Header.Get("X-Caller"); got != "test" {
+			t.Errorf("%s %s X-Caller = %q, want test", req.Method, req.Path, got)
+		}
+	}
+}
+
+func TestMiddlewareStepsReportToParent(t *testing.T) {
+	_, client, rec := newMiddlewareClient(t)
+
+	if ok, err := client.IsMember("u_test", "joeuser"); err != nil || !ok {
+		t.Fatalf("IsMember(joeuser) = %v, %v", ok, err)
+	}
+	if got := rec.last("IsMember").StatusCode; got != 200 {
+		t.Errorf("IsMember(joeuser) StatusCode = %d, want 200", got)
+	}
+	if ok, err := client.IsMember("u_test", "nobody"); err != nil || ok {
+		t.Fatalf("IsMember(nobody) = %v, %v", ok, err)
+	}
+	if got := rec.last("IsMember").StatusCode; got != 404 {
+		t.Errorf("IsMember(nobody) StatusCode = %d, want 404", got)
+	}
+
+	desired := gws.MemberList{}
+	desired.AppendMemberByID("joeuser", "janeuser")
+	if _, err := client.SyncMembership("u_test", desired, nil); err != nil {
+		t.Fatal(err)
+	}
+	if op := rec.last("SyncMembership"); op.StatusCode != 200 || op.MemberCount != 1 {
+		t.Errorf("SyncMembership StatusCode, MemberCount = %d, %d; want 200, 1", op.StatusCode, op.MemberCount)
+	}
+
+	if _, err := client.ModifyGroup("u_test", func(g *gws.Group) error {
+		g.Description = "changed"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.last("ModifyGroup").StatusCode; got != 200 {
+		t.Errorf("ModifyGroup StatusCode = %d, want 200", got)
+	}
+}
+
+func TestMiddlewareHandledErrors(t *testing.T) {
+	_, client, rec := newMiddlewareClient(t)
+
+	for _, tc := range []struct {
+		name    string
+		call    func() error
+		handled bool
+	}{
+		{"IsMember of non-member", func() error { _, err := client.IsMember("u_test", "nobody"); return err }, true},
+		{"IsEffectiveMember of non-member", func() error { _, err := client.IsEffectiveMember("u_test", "nobody"); return err }, true},
+		{"IsMember of missing group", func() error { _, err := client.IsMember("u_missing", "joeuser"); return err }, false},
+		{"GetMember of non-member", func() error { _, err := client.GetMember("u_test", "nobody"); return err }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec.ops, rec.errs = nil, nil
+			tc.call()
+			if len(rec.ops) == 0 {
+				t.Fatal("no operations recorded")
+			}
+			step, err := rec.ops[0], rec.errs[0]
+			if err == nil {
+				t.Fatalf("%s returned no error", step.Name)
+			}
+			if got := step.ErrorHandled(err); got != tc.handled {
+				t.Errorf("%s ErrorHandled(%v) = %v, want %v", step.Name, err, got, tc.handled)
+			}
+		})
+	}
+}
+
+func TestMiddlewareConcurrentSteps(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_test"})
+	ids, _, _ := chunkIDs(srv, 1000)
+	rec := &opRecorder{}
+	cfg := srv.Config()
+	cfg.Middleware = []gws.Middleware{rec}
+	cfg.MemberChunkConcurrency = 4
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run with -race: the chunks record their status on the operation at once
+	if _, err := client.AddMembers("u_test", ids...); err != nil {
+		t.Fatal(err)
+	}
+	if op := rec.last("AddMembers"); op.StatusCode != 200 || op.MemberCount != len(ids) {
+		t.Errorf("AddMembers StatusCode, MemberCount = %d, %d; want 200, %d", op.StatusCode, op.MemberCount, len(ids))
+	}
+}
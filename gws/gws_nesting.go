@@ -0,0 +1,316 @@
+package gws
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+)
+
+// NestingOptions controls AnalyzeNesting and AnalyzeStemNesting.
+type NestingOptions struct {
+	// MaxDepth is how many levels of groups are read, counting the root, along the shortest
+	// path to each group. Groups nested deeper are listed in NestingReport.Truncated. Zero
+	// means unlimited.
+	MaxDepth int
+
+	// MaxPaths is how many inclusion paths are listed per group in NestingReport.MultiPath,
+	// zero means 10. All paths are still counted in NestingReport.PathCount.
+	MaxPaths int
+
+	// Concurrency is how many group memberships are fetched at once, zero or one means
+	// one after another.
+	Concurrency int
+}
+
+// NestingReport describes the group inclusion graph under one or more root groups.
+type NestingReport struct {
+	// Roots are the groups the analysis started from
+	Roots []string
+
+	// Groups are all groups whose membership was read, sorted
+	Groups []string
+
+	// Nested maps each group to the groups that are its direct members
+	Nested map[string][]string
+
+	// Cycles are groups that include each other, each listed once starting at its
+	// lowest group ID and ending with that group again
+	Cycles [][]string
+
+	// MaxDepth is the number of groups in the longest inclusion chain, DeepestPath is that chain
+	MaxDepth    int
+	DeepestPath []string
+
+	// FanOut is the number of nested groups for each group that has any
+	FanOut map[string]int
+
+	// MultiPath are groups included through more than one path from the roots, with up to
+	// NestingOptions.MaxPaths of those paths. Paths that pass through a cycle are not counted.
+	MultiPath map[string][][]string
+
+	// PathCount is the number of inclusion paths to each group in MultiPath, which may be far
+	// more than are listed there. It stops at the largest int.
+	PathCount map[string]int
+
+	// Truncated are nested groups that were not read because of MaxDepth
+	Truncated []string
+
+	// Errors holds error messages for groups whose membership could not be read, by group ID
+	Errors map[string]string
+}
+
+// AnalyzeNesting builds the graph of groups nested under groupid from their direct
+// memberships and reports cycles, depth, fan-out and groups reachable by several paths.
+// opts may be nil.
+func (client *Client) AnalyzeNesting(groupid string, opts *NestingOptions) (*NestingReport, error) {
+	return client.AnalyzeNestingContext(context.Background(), groupid, opts)
+}
+
+// AnalyzeNestingContext is like AnalyzeNesting but carries ctx through to the API requests.
+func (client *Client) AnalyzeNestingContext(ctx context.Context, groupid string, opts *NestingOptions) (*NestingReport, error) {
+	return invokeResult(client, ctx, "AnalyzeNesting", groupid, func(ctx context.Context) (*NestingReport, error) {
+		errs := make(map[string]error)
+		report, err := client.analyzeNesting(ctx, []string{groupid}, opts, errs)
+		if err != nil {
+			return nil, err
+		}
+		if err := errs[groupid]; err != nil {
+			return nil, err
+		}
+		return report, nil
+	})
+}
+
+// AnalyzeStemNesting is like AnalyzeNesting for all groups under stem. Groups in the stem
+// that no other group in the stem includes are used as roots.
+func (client *Client) AnalyzeStemNesting(stem string, opts *NestingOptions) (*NestingReport, error) {
+	return client.AnalyzeStemNestingContext(context.Background(), stem, opts)
+}
+
+// AnalyzeStemNestingContext is like AnalyzeStemNesting but carries ctx through to the API requests.
+func (client *Client) AnalyzeStemNestingContext(ctx context.Context, stem string, opts *NestingOptions) (*NestingReport, error) {
+	return invokeResult(client, ctx, "AnalyzeStemNesting", "", func(ctx context.Context) (*NestingReport, error) {
+		refs, err := client.DoSearchContext(ctx, NewSearch().WithStem(stem).WithScope("all"))
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			ids = append(ids, ref.ID)
+		}
+		return client.analyzeNesting(ctx, ids, opts, make(map[string]error))
+	})
+}
+
+// analyzeNesting reads the memberships under candidates and walks the inclusion graph from
+// those candidates not nested in another candidate, then from any left unvisited.
+// Read failures are recorded in errs as well as in the report.
+func (client *Client) analyzeNesting(ctx context.Context, candidates []string, opts *NestingOptions, errs map[string]error) (*NestingReport, error) {
+	if opts == nil {
+		opts = &NestingOptions{}
+	}
+
+	memberships, err := client.fetchNested(ctx, candidates, opts.MaxDepth, opts.Concurrency, errs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &NestingReport{
+		Nested:    make(map[string][]string),
+		FanOut:    make(map[string]int),
+		MultiPath: make(map[string][][]string),
+		PathCount: make(map[string]int),
+		Errors:    make(map[string]string),
+	}
+	for gid, err := range errs {
+		report.Errors[gid] = err.Error()
+	}
+	for gid, members := range memberships {
+		report.Groups = append(report.Groups, gid)
+		for _, m := range members {
+			if m.Type == MemberTypeGroup && !slices.Contains(report.Nested[gid], m.ID) {
+				report.Nested[gid] = append(report.Nested[gid], m.ID)
+			}
+		}
+		if n := len(report.Nested[gid]); n > 0 {
+			report.FanOut[gid] = n
+		}
+	}
+	slices.Sort(report.Groups)
+
+	included := make(map[string]bool)
+	for _, gid := range candidates {
+		for _, sub := range report.Nested[gid] {
+			if sub != gid && slices.Contains(candidates, sub) {
+				included[sub] = true
+			}
+		}
+	}
+
+	g := nestingGraph{
+		report:      report,
+		memberships: memberships,
+		errs:        errs,
+		state:       make(map[string]int),
+		depth:       make(map[string]int),
+		next:        make(map[string]string),
+		parents:     make(map[string][]string),
+		seenCycle:   make(map[string]bool),
+	}
+	for _, gid := range candidates {
+		if _, fetched := memberships[gid]; fetched && !included[gid] && g.state[gid] == unvisited {
+			report.Roots = append(report.Roots, gid)
+			g.visit(gid, nil)
+		}
+	}
+	// Candidates that only include each other have no top level group
+	for _, gid := range candidates {
+		if _, fetched := memberships[gid]; fetched && g.state[gid] == unvisited {
+			report.Roots = append(report.Roots, gid)
+			g.visit(gid, nil)
+		}
+	}
+
+	for _, root := range report.Roots {
+		if g.depth[root] > report.MaxDepth {
+			report.MaxDepth = g.depth[root]
+			report.DeepestPath = g.deepestFrom(root)
+		}
+	}
+	maxPaths := opts.MaxPaths
+	if maxPaths <= 0 {
+		maxPaths = defaultMaxPaths
+	}
+	g.countPaths(maxPaths)
+	return report, nil
+}
+
+// defaultMaxPaths is how many inclusion paths are kept per group when NestingOptions.MaxPaths is zero.
+const defaultMaxPaths = 10
+
+// DFS states of a group in nestingGraph.
+const (
+	unvisited = iota
+	onStack
+	finished
+)
+
+// nestingGraph walks the inclusion graph once, depth first. Edges that close a cycle are
+// reported and then left out, which leaves a DAG on which depths and path counts are
+// computed with memoization rather than by listing every path.
+type nestingGraph struct {
+	report      *NestingReport
+	memberships map[string]MemberList
+	errs        map[string]error
+
+	state     map[string]int
+	order     []string            // groups in DFS post-order
+	depth     map[string]int      // groups in the longest chain starting at the group
+	next      map[string]string   // the nested group that chain continues with
+	parents   map[string][]string // DAG predecessors of each group
+	seenCycle map[string]bool
+}
+
+// visit walks the groups nested under gid, stack holding the groups above it.
+func (g *nestingGraph) visit(gid string, stack []string) {
+	g.state[gid] = onStack
+	stack = append(stack, gid)
+	g.depth[gid] = 1
+	for _, sub := range g.report.Nested[gid] {
+		switch g.state[sub] {
+		case onStack:
+			cycle := canonicalCycle(stack[slices.Index(stack, sub):])
+			if key := strings.Join(cycle, ","); !g.seenCycle[key] {
+				g.seenCycle[key] = true
+				g.report.Cycles = append(g.report.Cycles, cycle)
+			}
+			continue
+		case unvisited:
+			if _, fetched := g.memberships[sub]; !fetched {
+				if _, failed := g.errs[sub]; !failed && !slices.Contains(g.report.Truncated, sub) {
+					g.report.Truncated = append(g.report.Truncated, sub)
+				}
+				continue
+			}
+			g.visit(sub, stack)
+		}
+		g.parents[sub] = append(g.parents[sub], gid)
+		if d := 1 + g.depth[sub]; d > g.depth[gid] {
+			g.depth[gid] = d
+			g.next[gid] = sub
+		}
+	}
+	g.state[gid] = finished
+	g.order = append(g.order, gid)
+}
+
+// deepestFrom follows the longest chain starting at root.
+func (g *nestingGraph) deepestFrom(root string) []string {
+	path := []string{root}
+	for gid := root; g.next[gid] != ""; {
+		gid = g.next[gid]
+		path = append(path, gid)
+	}
+	return path
+}
+
+// countPaths counts the inclusion paths from the roots to every group, in topological order,
+// and fills in MultiPath and PathCount for groups with more than one. Up to maxPaths paths
+// are listed per group.
+func (g *nestingGraph) countPaths(maxPaths int) {
+	counts := make(map[string]int)
+	for _, root := range g.report.Roots {
+		counts[root] = 1
+	}
+	for i := len(g.order) - 1; i >= 0; i-- {
+		gid := g.order[i]
+		for _, p := range g.parents[gid] {
+			counts[gid] = saturatingAdd(counts[gid], counts[p])
+		}
+		if counts[gid] > 1 {
+			g.report.PathCount[gid] = counts[gid]
+			g.report.MultiPath[gid] = listPaths(g.parents, gid, maxPaths)
+		}
+	}
+}
+
+// listPaths lists up to max paths from a root to gid by walking up the DAG predecessors in
+// parents. Every predecessor leads back to a root, so no branch is a dead end.
+func listPaths(parents map[string][]string, gid string, max int) [][]string {
+	var paths [][]string
+	var up func(gid string, below []string)
+	up = func(gid string, below []string) {
+		if len(paths) >= max {
+			return
+		}
+		below = append(below, gid)
+		if len(parents[gid]) == 0 {
+			path := slices.Clone(below)
+			slices.Reverse(path)
+			paths = append(paths, path)
+			return
+		}
+		for _, p := range parents[gid] {
+			up(p, below)
+		}
+	}
+	up(gid, nil)
+	return paths
+}
+
+// saturatingAdd adds two path counts, stopping at the largest int.
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// canonicalCycle closes the cycle of groups in loop and rotates it to start at the lowest ID,
+// so the same cycle found from different starting points compares equal.
+func canonicalCycle(loop []string) []string {
+	start := slices.Index(loop, slices.Min(loop))
+	cycle := append(slices.Clone(loop[start:]), loop[:start]...)
+	return append(cycle, cycle[0])
+}
@@ -0,0 +1,96 @@
+package gws_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// addNestedGroup adds group id to srv with the given groups as its members.
+func addNestedGroup(srv *gwstest.Server, id string, nested ...string) {
+	members := make([]gws.Member, 0, len(nested))
+	for _, sub := range nested {
+		members = append(members, gws.Member{Type: gws.MemberTypeGroup, ID: sub})
+	}
+	srv.AddGroup(gws.Group{ID: id}, members...)
+}
+
+func TestAnalyzeNestingLayeredGraph(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+
+	// u_root includes both groups of layer 1, and each group of a layer includes both
+	// groups of the next, so there are 2^(layers-1) paths to each group of the last layer.
+	const layers = 40
+	layer := func(i int) []string { return []string{fmt.Sprintf("u_l%02d_a", i), fmt.Sprintf("u_l%02d_b", i)} }
+	addNestedGroup(srv, fmt.Sprintf("u_l%02d_a", layers))
+	addNestedGroup(srv, fmt.Sprintf("u_l%02d_b", layers))
+	for i := layers - 1; i >= 1; i-- {
+		for _, gid := range layer(i) {
+			addNestedGroup(srv, gid, layer(i+1)...)
+		}
+	}
+	addNestedGroup(srv, "u_root", layer(1)...)
+
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := client.AnalyzeNesting("u_root", &gws.NestingOptions{MaxPaths: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.MaxDepth != layers+1 || len(report.DeepestPath) != layers+1 {
+		t.Errorf("MaxDepth = %d with path of %d, want %d", report.MaxDepth, len(report.DeepestPath), layers+1)
+	}
+	if len(report.Cycles) != 0 {
+		t.Errorf("Cycles = %v, want none", report.Cycles)
+	}
+	last := fmt.Sprintf("u_l%02d_a", layers)
+	if got, want := report.PathCount[last], 1<<(layers-1); got != want {
+		t.Errorf("PathCount[%s] = %d, want %d", last, got, want)
+	}
+	if got := len(report.MultiPath[last]); got != 3 {
+		t.Errorf("MultiPath[%s] has %d paths, want 3", last, got)
+	}
+	for _, path := range report.MultiPath[last] {
+		if len(path) != layers+1 || path[0] != "u_root" || path[len(path)-1] != last {
+			t.Errorf("MultiPath[%s] path %v does not lead from u_root", last, path)
+		}
+	}
+	if _, ok := report.MultiPath["u_l01_a"]; ok {
+		t.Errorf("u_l01_a has one path but is in MultiPath")
+	}
+}
+
+func TestAnalyzeNestingCycle(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	addNestedGroup(srv, "u_root", "u_b", "u_c")
+	addNestedGroup(srv, "u_b", "u_c")
+	addNestedGroup(srv, "u_c", "u_d")
+	addNestedGroup(srv, "u_d", "u_b")
+
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := client.AnalyzeNesting("u_root", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Cycles) != 1 || !slices.Equal(report.Cycles[0], []string{"u_b", "u_c", "u_d", "u_b"}) {
+		t.Errorf("Cycles = %v, want [[u_b u_c u_d u_b]]", report.Cycles)
+	}
+	if want := []string{"u_root", "u_b", "u_c", "u_d"}; !slices.Equal(report.DeepestPath, want) {
+		t.Errorf("DeepestPath = %v, want %v", report.DeepestPath, want)
+	}
+	if got := report.PathCount["u_c"]; got != 2 {
+		t.Errorf("PathCount[u_c] = %d, want 2", got)
+	}
+}
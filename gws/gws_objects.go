@@ -12,7 +12,7 @@ type DNSString string
 // UWWIString a Microsoft Infrastructure (MI) machine name (with a $ appended)
 type UWWIString string
 
-// Affiliate an affiliate
+// Affiliate an affiliate name
 // enum [ email, google, uwnetid, radius ]
 type Affiliate string
 
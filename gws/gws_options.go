@@ -0,0 +1,79 @@
+package gws
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RequestOption customizes a single write request without touching shared client state,
+// so options are safe to use from many goroutines sharing one Client.
+type RequestOption func(*requestOptions)
+
+// requestOptions collects the effect of RequestOptions for one call.
+type requestOptions struct {
+	synchronized bool
+	ifMatch      string
+	force        bool
+	headers      map[string]string
+}
+
+// WithSynchronized makes the write wait for cache propagation before returning,
+// so that subsequent reads immediately see the change.
+func WithSynchronized() RequestOption {
+	return func(o *requestOptions) {
+		o.synchronized = true
+	}
+}
+
+// WithIfMatch sends an If-Match header so the write only succeeds if the resource
+// still has the given ETag. It overrides the ETag carried by a Group.
+func WithIfMatch(etag string) RequestOption {
+	return func(o *requestOptions) {
+		o.ifMatch = etag
+	}
+}
+
+// WithForce lets UpdateGroup save a group that carries no ETag. The update is then sent
+// without If-Match and overwrites whatever changes were made since the group was read.
+func WithForce() RequestOption {
+	return func(o *requestOptions) {
+		o.force = true
+	}
+}
+
+// WithHeader adds an arbitrary header to the request.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		o.headers[key] = value
+	}
+}
+
+// resolveOptions applies opts over the client defaults.
+func (client *Client) resolveOptions(opts []RequestOption) requestOptions {
+	o := requestOptions{synchronized: client.synchronized.Load()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// writeRequest returns a request for a write operation with the client defaults and opts applied.
+func (client *Client) writeRequest(ctx context.Context, opts []RequestOption) *resty.Request {
+	o := client.resolveOptions(opts)
+	req := client.request(ctx)
+	if o.synchronized {
+		// Value doesn't matter, only presence/absence
+		req.SetQueryParam("synchronized", "true")
+	}
+	for k, v := range o.headers {
+		req.SetHeader(k, v)
+	}
+	if o.ifMatch != "" {
+		req.SetHeader("If-Match", o.ifMatch)
+	}
+	return req
+}
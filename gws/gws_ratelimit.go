@@ -0,0 +1,149 @@
+package gws
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket that refills at limit tokens per second up to burst.
+// A 429 response halves the current limit and pauses the bucket; each successful
+// response then restores a little of the configured rate.
+type rateLimiter struct {
+	mu          sync.Mutex
+	max         float64 // configured requests per second
+	limit       float64 // current requests per second, lowered after throttling
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// newRateLimiter creates a full bucket allowing perSecond requests per second.
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		max:    perSecond,
+		limit:  perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.refill(now)
+		var delay time.Duration
+		switch {
+		case now.Before(l.pausedUntil):
+			delay = l.pausedUntil.Sub(now)
+		case l.tokens >= 1:
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		default:
+			delay = time.Duration((1 - l.tokens) / l.limit * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// refill adds the tokens earned since the last call. Caller holds l.mu.
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.limit
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// throttled reacts to a 429 by halving the rate and pausing for retryAfter.
+func (l *rateLimiter) throttled(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = max(l.limit/2, l.max/16)
+	l.tokens = 0
+	if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// succeeded recovers part of the configured rate after a throttle.
+func (l *rateLimiter) succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = min(l.limit+l.max/20, l.max)
+}
+
+// limitTransport applies the client rate limit and concurrency cap to every HTTP attempt,
+// including retries.
+type limitTransport struct {
+	next     http.RoundTripper
+	limiter  *rateLimiter
+	inFlight chan struct{}
+}
+
+// RoundTrip waits for a rate token and an in-flight slot before sending req.
+// The slot is held until the response body is closed.
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if t.limiter != nil {
+		if err := t.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	release := func() {}
+	if t.inFlight != nil {
+		select {
+		case t.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-t.inFlight }
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if t.limiter != nil {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			t.limiter.throttled(parseRetryAfter(resp.Header.Get("Retry-After")))
+		} else {
+			t.limiter.succeeded()
+		}
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseOnClose frees an in-flight slot once the response body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the body and releases the slot exactly once.
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
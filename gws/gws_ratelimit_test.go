@@ -0,0 +1,175 @@
+package gws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPacing(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 2 {
+		if err := l.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 5*time.Millisecond {
+		t.Errorf("burst of 2 took %v, want no wait", d)
+	}
+	for range 5 {
+		if err := l.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Five more tokens at 100 per second
+	if d := time.Since(start); d < 40*time.Millisecond || d > time.Second {
+		t.Errorf("7 requests took %v, want about 50ms", d)
+	}
+}
+
+func TestRateLimiterThrottleAndRecover(t *testing.T) {
+	l := newRateLimiter(100, 1)
+
+	for _, want := range []float64{50, 25, 12.5, 6.25, 6.25} {
+		l.throttled(0)
+		if l.limit != want {
+			t.Fatalf("limit after 429 = %v, want %v", l.limit, want)
+		}
+		if l.tokens != 0 {
+			t.Fatalf("tokens after 429 = %v, want 0", l.tokens)
+		}
+	}
+	for _, want := range []float64{11.25, 16.25} {
+		l.succeeded()
+		if l.limit != want {
+			t.Fatalf("limit after success = %v, want %v", l.limit, want)
+		}
+	}
+	for range 50 {
+		l.succeeded()
+	}
+	if l.limit != 100 {
+		t.Errorf("limit after many successes = %v, want 100", l.limit)
+	}
+}
+
+func TestRateLimiterRetryAfterPause(t *testing.T) {
+	l := newRateLimiter(1000, 10)
+	l.throttled(50 * time.Millisecond)
+
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Errorf("wait during pause took %v, want at least 50ms", d)
+	}
+
+	// A shorter Retry-After does not cut an existing pause short
+	l.throttled(time.Hour)
+	l.throttled(time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait during long pause = %v, want deadline exceeded", err)
+	}
+}
+
+// stubTransport answers every request with status, or fails with err.
+type stubTransport struct {
+	status int
+	header http.Header
+	err    error
+}
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{
+		StatusCode: s.status,
+		Header:     s.header,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func newLimitRequest(t *testing.T, ctx context.Context) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://gws.test/group/u_test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestLimitTransportInFlight(t *testing.T) {
+	lt := &limitTransport{next: stubTransport{status: http.StatusOK}, inFlight: make(chan struct{}, 1)}
+
+	resp, err := lt.RoundTrip(newLimitRequest(t, context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lt.inFlight) != 1 {
+		t.Fatalf("in flight with open body = %d, want 1", len(lt.inFlight))
+	}
+
+	// The only slot is taken, so a second request waits until its context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := lt.RoundTrip(newLimitRequest(t, ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip with no free slot = %v, want deadline exceeded", err)
+	}
+
+	resp.Body.Close()
+	resp.Body.Close()
+	if len(lt.inFlight) != 0 {
+		t.Fatalf("in flight after close = %d, want 0", len(lt.inFlight))
+	}
+
+	lt.next = stubTransport{err: errors.New("connection refused")}
+	if _, err := lt.RoundTrip(newLimitRequest(t, context.Background())); err == nil {
+		t.Fatal("RoundTrip succeeded, want the transport error")
+	}
+	if len(lt.inFlight) != 0 {
+		t.Errorf("in flight after a failed request = %d, want 0", len(lt.inFlight))
+	}
+}
+
+func TestLimitTransportThrottles(t *testing.T) {
+	l := newRateLimiter(100, 10)
+	lt := &limitTransport{
+		next:    stubTransport{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"1"}}},
+		limiter: l,
+	}
+	resp, err := lt.RoundTrip(newLimitRequest(t, context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if l.limit != 50 {
+		t.Errorf("limit after 429 = %v, want 50", l.limit)
+	}
+	if until := time.Until(l.pausedUntil); until < 900*time.Millisecond || until > time.Second {
+		t.Errorf("paused for %v, want the 1s of Retry-After", until)
+	}
+
+	lt.next = stubTransport{status: http.StatusOK}
+	l.pausedUntil = time.Time{}
+	l.tokens = 1
+	resp, err = lt.RoundTrip(newLimitRequest(t, context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if l.limit != 55 {
+		t.Errorf("limit after a success = %v, want 55", l.limit)
+	}
+}
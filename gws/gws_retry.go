@@ -0,0 +1,125 @@
+package gws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RetryPolicy controls how the client retries requests that fail for transient reasons.
+// GETs are always eligible for retry. Writes are retried only when repeating them is safe:
+// group updates guarded by an ETag If-Match header and full membership replacements.
+// Other writes, such as adding members, are never retried, If-Match or not.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry. Later waits grow exponentially
+	// with random jitter, up to MaxBackoff.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts, including waits requested by Retry-After.
+	MaxBackoff time.Duration
+
+	// RetryableStatuses lists the HTTP statuses that are worth retrying.
+	RetryableStatuses []int
+
+	// RespectRetryAfter waits for the duration given in a Retry-After response header when present.
+	RespectRetryAfter bool
+}
+
+// DefaultRetryPolicy returns the retry policy used by DefaultConfig.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RespectRetryAfter: true,
+	}
+}
+
+// apply installs the policy on the resty client.
+func (p *RetryPolicy) apply(r *resty.Client) {
+	if p == nil || p.MaxAttempts < 2 {
+		return
+	}
+	r.SetRetryCount(p.MaxAttempts - 1)
+	r.SetRetryWaitTime(p.InitialBackoff)
+	r.SetRetryMaxWaitTime(p.MaxBackoff)
+	r.AddRetryCondition(p.shouldRetry)
+	r.AddRetryHook(func(resp *resty.Response, _ error) {
+		// Streamed responses are not read by resty, so the body of an attempt that is about
+		// to be repeated must be closed here to free its connection and in-flight slot. The
+		// hook also runs after the last attempt, whose response goes back to the caller.
+		if resp != nil && resp.RawResponse != nil && resp.Request.Attempt <= r.RetryCount {
+			resp.RawBody().Close()
+		}
+	})
+	if p.RespectRetryAfter {
+		r.SetRetryAfter(retryAfter)
+	}
+}
+
+// shouldRetry decides whether a failed attempt is retried.
+func (p *RetryPolicy) shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil || !retrySafe(resp.Request) {
+		return false
+	}
+	if err != nil && resp.RawResponse == nil {
+		// Transport failure: connection reset, timeout and the like, but not our own cancellation.
+		return !errors.Is(err, context.Canceled) && resp.Request.Context().Err() == nil
+	}
+	return slices.Contains(p.RetryableStatuses, resp.StatusCode())
+}
+
+// retrySafeKey marks a request context as safe to retry regardless of method.
+type retrySafeKey struct{}
+
+// withRetrySafe marks requests made with the returned context as safe to repeat.
+func withRetrySafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, true)
+}
+
+// retrySafe reports whether repeating the request cannot cause an unintended change.
+// Reads always are; writes only when marked with withRetrySafe.
+func retrySafe(req *resty.Request) bool {
+	switch req.Method {
+	case resty.MethodGet, resty.MethodHead:
+		return true
+	}
+	safe, _ := req.Context().Value(retrySafeKey{}).(bool)
+	return safe
+}
+
+// retryAfter reads the Retry-After header, in either delay-seconds or HTTP-date form.
+// A zero duration tells resty to use its own backoff.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	return parseRetryAfter(resp.Header().Get("Retry-After")), nil
+}
+
+// parseRetryAfter converts a Retry-After header value to a duration, zero if absent or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
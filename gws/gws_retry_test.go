@@ -0,0 +1,100 @@
+package gws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// newRetryRequest returns a request with the given method, If-Match header and context.
+func newRetryRequest(ctx context.Context, method, ifMatch string) *resty.Request {
+	req := resty.New().R().SetContext(ctx)
+	req.Method = method
+	if ifMatch != "" {
+		req.SetHeader("If-Match", ifMatch)
+	}
+	return req
+}
+
+func TestRetrySafe(t *testing.T) {
+	bg := context.Background()
+	for _, tc := range []struct {
+		name string
+		req  *resty.Request
+		want bool
+	}{
+		{"GET", newRetryRequest(bg, resty.MethodGet, ""), true},
+		{"HEAD", newRetryRequest(bg, resty.MethodHead, ""), true},
+		{"PUT", newRetryRequest(bg, resty.MethodPut, ""), false},
+		{"PUT with If-Match", newRetryRequest(bg, resty.MethodPut, `"etag"`), false},
+		{"PUT marked safe", newRetryRequest(withRetrySafe(bg), resty.MethodPut, ""), true},
+		{"DELETE", newRetryRequest(bg, resty.MethodDelete, ""), false},
+		{"DELETE marked safe", newRetryRequest(withRetrySafe(bg), resty.MethodDelete, ""), true},
+		{"POST", newRetryRequest(bg, resty.MethodPost, ""), false},
+	} {
+		if got := retrySafe(tc.req); got != tc.want {
+			t.Errorf("retrySafe(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	p := DefaultRetryPolicy()
+	get := newRetryRequest(context.Background(), resty.MethodGet, "")
+	put := newRetryRequest(context.Background(), resty.MethodPut, `"etag"`)
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := newRetryRequest(canceledCtx, resty.MethodGet, "")
+	status := func(req *resty.Request, code int) *resty.Response {
+		return &resty.Response{Request: req, RawResponse: &http.Response{StatusCode: code}}
+	}
+	failed := func(req *resty.Request) *resty.Response { return &resty.Response{Request: req} }
+	connErr := errors.New("connection reset by peer")
+
+	for _, tc := range []struct {
+		name string
+		resp *resty.Response
+		err  error
+		want bool
+	}{
+		{"GET 503", status(get, http.StatusServiceUnavailable), nil, true},
+		{"GET 429", status(get, http.StatusTooManyRequests), nil, true},
+		{"GET 500", status(get, http.StatusInternalServerError), nil, false},
+		{"GET 404", status(get, http.StatusNotFound), nil, false},
+		{"GET 200", status(get, http.StatusOK), nil, false},
+		{"PUT 503", status(put, http.StatusServiceUnavailable), nil, false},
+		{"GET connection error", failed(get), connErr, true},
+		{"PUT connection error", failed(put), connErr, false},
+		{"GET canceled", failed(canceled), context.Canceled, false},
+		{"GET after cancel", failed(canceled), connErr, false},
+		{"no response", nil, connErr, false},
+	} {
+		if got := p.shouldRetry(tc.resp, tc.err); got != tc.want {
+			t.Errorf("shouldRetry(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"0", 0, 0},
+		{"-5", 0, 0},
+		{"soon", 0, 0},
+		{"3", 3 * time.Second, 3 * time.Second},
+		{"120", 2 * time.Minute, 2 * time.Minute},
+		{time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), 28 * time.Second, 30 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	} {
+		if got := parseRetryAfter(tc.value); got < tc.min || got > tc.max {
+			t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tc.value, got, tc.min, tc.max)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package gws
 
-import "fmt"
+import (
+	"context"
+	"net/url"
+)
 
 // searchResponse is returned from a Group search.
 type searchResponse struct {
@@ -58,34 +61,42 @@ type GroupReference struct {
 
 // SearchParameters holds the parameters to submit for a group search.
 type SearchParameters struct {
-	parameters map[string]string
+	parameters url.Values
 }
 
 // NewSearch creates a blank query to build up and submit for searching.
 func NewSearch() *SearchParameters {
-	return &SearchParameters{parameters: make(map[string]string)}
+	return &SearchParameters{parameters: make(url.Values)}
 }
 
 // DoSearch submits a search for groups matching the supplied search parameters.
 func (client *Client) DoSearch(s *SearchParameters) ([]GroupReference, error) {
-	var gr []GroupReference
+	return client.DoSearchContext(context.Background(), s)
+}
 
-	resp, err := client.request().
-		SetResult(searchResponse{}).
-		Get(fmt.Sprintf("/search?%s", s.queryString()))
-	if err != nil {
-		return gr, err
-	}
-	if resp.IsError() {
-		return gr, formatErrorResponse(resp.Error().(*errorResponse))
-	}
-	return resp.Result().(*searchResponse).Data, nil
+// DoSearchContext is like DoSearch but carries ctx through to the API request.
+func (client *Client) DoSearchContext(ctx context.Context, s *SearchParameters) ([]GroupReference, error) {
+	return invokeResult(client, ctx, "DoSearch", "", func(ctx context.Context) ([]GroupReference, error) {
+		var gr []GroupReference
+
+		resp, err := client.request(ctx).
+			SetResult(searchResponse{}).
+			SetQueryParamsFromValues(s.parameters).
+			Get("/search")
+		if err != nil {
+			return gr, err
+		}
+		if resp.IsError() {
+			return gr, newAPIError(resp)
+		}
+		return resp.Result().(*searchResponse).Data, nil
+	})
 }
 
 // WithName adds a match on name. Name is some part of the group id, "*" is a wildcard.
 func (s *SearchParameters) WithName(name string) *SearchParameters {
 	if name != "" {
-		s.parameters["name"] = name
+		s.parameters.Set("name", name)
 	}
 	return s
 }
@@ -93,7 +104,7 @@ func (s *SearchParameters) WithName(name string) *SearchParameters {
 // WithStem adds a match on stem. Stem is the stem part of the group id.
 func (s *SearchParameters) WithStem(stem string) *SearchParameters {
 	if stem != "" {
-		s.parameters["stem"] = stem
+		s.parameters.Set("stem", stem)
 	}
 	return s
 }
@@ -101,7 +112,7 @@ func (s *SearchParameters) WithStem(stem string) *SearchParameters {
 // WithScope adds a match on scope.
 func (s *SearchParameters) WithScope(scope string) *SearchParameters {
 	if scope != "" {
-		s.parameters["scope"] = scope
+		s.parameters.Set("scope", scope)
 	}
 	return s
 }
@@ -109,27 +120,27 @@ func (s *SearchParameters) WithScope(scope string) *SearchParameters {
 // WithMember adds match for groups with the specified member id.
 func (s *SearchParameters) WithMember(id string) *SearchParameters {
 	if id != "" {
-		s.parameters["member"] = id
+		s.parameters.Set("member", id)
 	}
 	return s
 }
 
 // InEffectiveMembers matches effective members when searching for members, owners, instructors.
 func (s *SearchParameters) InEffectiveMembers() *SearchParameters {
-	s.parameters["type"] = "effective"
+	s.parameters.Set("type", "effective")
 	return s
 }
 
 // InDirectMembers matches direct members when searching for members, owners, instructors, this is default.
 func (s *SearchParameters) InDirectMembers() *SearchParameters {
-	s.parameters["type"] = "direct"
+	s.parameters.Set("type", "direct")
 	return s
 }
 
 // WithOwner adds match for groups where an administrator (admin, creator, updater, member manager) is the specified id.
 func (s *SearchParameters) WithOwner(id string) *SearchParameters {
 	if id != "" {
-		s.parameters["owner"] = id
+		s.parameters.Set("owner", id)
 	}
 	return s
 }
@@ -137,7 +148,7 @@ func (s *SearchParameters) WithOwner(id string) *SearchParameters {
 // WithInstructor adds match for groups where the instructor is the specified id.
 func (s *SearchParameters) WithInstructor(id string) *SearchParameters {
 	if id != "" {
-		s.parameters["instructor"] = id
+		s.parameters.Set("instructor", id)
 	}
 	return s
 }
@@ -145,22 +156,7 @@ func (s *SearchParameters) WithInstructor(id string) *SearchParameters {
 // WithAffiliate adds match for groups where the affiliate is the specified id. The affiliate search ignores any other search parameters.
 func (s *SearchParameters) WithAffiliate(id string) *SearchParameters {
 	if id != "" {
-		s.parameters["affiliate"] = id
+		s.parameters.Set("affiliate", id)
 	}
 	return s
 }
-
-// queryString assembles the parameters into a query string
-func (s *SearchParameters) queryString() string {
-	qs := ""
-	separator := ""
-
-	for k, v := range s.parameters {
-		if v == "" {
-			continue
-		}
-		qs = qs + fmt.Sprintf("%s%s=%s", separator, k, v)
-		separator = "&"
-	}
-	return qs
-}
@@ -0,0 +1,56 @@
+package gws_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+func TestSearchEscaping(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddPeople("joeuser")
+	srv.AddGroup(gws.Group{ID: "u_stem_a"}, gws.Member{Type: gws.MemberTypeEPPN, ID: "joe+test@example.edu"})
+	srv.AddGroup(gws.Group{ID: "u_stem_b-c"})
+	srv.AddGroup(gws.Group{ID: "u_other_a"})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		search *gws.SearchParameters
+		query  string
+		want   []string
+	}{
+		{"trailing wildcard", gws.NewSearch().WithName("u_stem_*"), "name=u_stem_%2A", []string{"u_stem_a", "u_stem_b-c"}},
+		{"inner wildcard", gws.NewSearch().WithName("u_*_a"), "name=u_%2A_a", []string{"u_other_a", "u_stem_a"}},
+		{"stem", gws.NewSearch().WithStem("u_stem").WithScope("one"), "scope=one&stem=u_stem", []string{"u_stem_a", "u_stem_b-c"}},
+		{"eppn member", gws.NewSearch().WithMember("joe+test@example.edu"), "member=joe%2Btest%40example.edu", []string{"u_stem_a"}},
+		{"reserved characters", gws.NewSearch().WithName("a&b=c#d"), "name=a%26b%3Dc%23d", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(srv.Requests())
+			refs, err := client.DoSearch(tc.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ref := range refs {
+				got = append(got, ref.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("groups = %v, want %v", got, tc.want)
+			}
+
+			reqs := srv.Requests()[before:]
+			if len(reqs) != 1 || reqs[0].Path != "/search" || reqs[0].Query != tc.query {
+				t.Errorf("requests = %+v, want GET /search?%s", reqs, tc.query)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package gws
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"weak"
+)
+
+// CompareOption changes how the set operations of MemberList and EntityList match members.
+type CompareOption func(*compareOptions)
+
+// compareOptions holds the settings made by CompareOptions.
+type compareOptions struct {
+	foldCase bool
+}
+
+// IgnoreUWNetIDCase matches UWNetIDs regardless of case, so that JDoe and jdoe are the same
+// member. IDs of other types, such as groups, are still compared exactly.
+func IgnoreUWNetIDCase() CompareOption {
+	return func(o *compareOptions) {
+		o.foldCase = true
+	}
+}
+
+// setKey identifies a member or entity in set operations.
+type setKey struct {
+	typ string
+	id  string
+}
+
+// setElement is implemented by Member and Entity.
+type setElement interface {
+	Member | Entity
+	setKey(o compareOptions) setKey
+}
+
+func (m Member) setKey(o compareOptions) setKey { return newSetKey(string(m.Type), m.ID, o) }
+
+func (e Entity) setKey(o compareOptions) setKey { return newSetKey(e.Type, e.ID, o) }
+
+// newSetKey returns the key for typ and id, folding the case of UWNetIDs if o asks for it.
+func newSetKey(typ, id string, o compareOptions) setKey {
+	if o.foldCase && typ == EntityTypeUWNetID {
+		id = strings.ToLower(id)
+	}
+	return setKey{typ: typ, id: id}
+}
+
+// resolveCompareOptions applies opts over the defaults.
+func resolveCompareOptions(opts []CompareOption) compareOptions {
+	var o compareOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// keySet returns the keys of the elements of list.
+func keySet[T setElement](list []T, o compareOptions) map[setKey]struct{} {
+	keys := make(map[setKey]struct{}, len(list))
+	for _, v := range list {
+		keys[v.setKey(o)] = struct{}{}
+	}
+	return keys
+}
+
+// selectKeys returns a new list of the elements of list whose key is in keys, or not in keys
+// if in is false, keeping only the first element with each key and the order of list.
+func selectKeys[T setElement](list []T, keys map[setKey]struct{}, in bool, o compareOptions) []T {
+	seen := make(map[setKey]struct{}, len(list))
+	out := make([]T, 0)
+	for _, v := range list {
+		k := v.setKey(o)
+		if _, dup := seen[k]; dup {
+			continue
+		}
+		seen[k] = struct{}{}
+		if _, ok := keys[k]; ok == in {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// union returns the elements of a and then those of b not in a, without duplicates.
+func union[T setElement](a, b []T, o compareOptions) []T {
+	return selectKeys(slices.Concat(a, b), nil, false, o)
+}
+
+// difference returns the elements of a not in b, without duplicates.
+func difference[T setElement](a, b []T, o compareOptions) []T {
+	return selectKeys(a, keySet(b, o), false, o)
+}
+
+// sortByKey sorts list by type and then ID.
+func sortByKey[T setElement](list []T) {
+	slices.SortStableFunc(list, func(a, b T) int {
+		ka, kb := a.setKey(compareOptions{}), b.setKey(compareOptions{})
+		return cmp.Or(cmp.Compare(ka.typ, kb.typ), cmp.Compare(ka.id, kb.id))
+	})
+}
+
+// removeIDs removes from list the first element with each of ids, in one pass. It reuses
+// the backing array of list.
+func removeIDs[T setElement](list []T, ids []string) []T {
+	if len(ids) == 0 {
+		return list
+	}
+	pending := make(map[string]int, len(ids))
+	for _, id := range ids {
+		pending[id]++
+	}
+	out := list[:0]
+	for _, v := range list {
+		if id := v.setKey(compareOptions{}).id; pending[id] > 0 {
+			pending[id]--
+			continue
+		}
+		out = append(out, v)
+	}
+	clear(list[len(out):])
+	return out
+}
+
+// Index looks up IDs in a MemberList or EntityList in constant time. Build one with
+// MemberList.Index or EntityList.Index to check many IDs against a large list; it does
+// not follow later changes to the list.
+type Index struct {
+	ids    map[string]struct{}
+	folded map[string]struct{} // lower case UWNetIDs, with IgnoreUWNetIDCase
+	opts   compareOptions
+}
+
+// newIndex returns an Index of the elements of list.
+func newIndex[T setElement](list []T, o compareOptions) *Index {
+	ix := &Index{ids: make(map[string]struct{}, len(list)), opts: o}
+	if o.foldCase {
+		ix.folded = make(map[string]struct{})
+	}
+	for _, v := range list {
+		ix.add(v.setKey(compareOptions{}))
+	}
+	return ix
+}
+
+// add puts the member or entity with key k in the index.
+func (ix *Index) add(k setKey) {
+	ix.ids[k.id] = struct{}{}
+	if ix.opts.foldCase && k.typ == EntityTypeUWNetID {
+		ix.folded[strings.ToLower(k.id)] = struct{}{}
+	}
+}
+
+// Contains reports whether the indexed list has a member or entity with the given ID, like
+// MemberList.Contains and EntityList.Contains.
+func (ix *Index) Contains(id string) bool {
+	if _, ok := ix.ids[id]; ok {
+		return true
+	}
+	if ix.opts.foldCase {
+		_, ok := ix.folded[strings.ToLower(id)]
+		return ok
+	}
+	return false
+}
+
+// containsScanMax is the longest list Contains scans; longer lists are looked up in an
+// index built the first time they are asked about.
+const containsScanMax = 32
+
+// listKey identifies a list by its backing array and length. The weak pointer lets the
+// array be collected while its index is cached.
+type listKey[T setElement] struct {
+	first weak.Pointer[T]
+	n     int
+}
+
+// listIndex holds the position of the first element with each ID, and the IDs of the
+// first and last elements as a cheap check that the list was not refilled since.
+type listIndex struct {
+	pos         map[string]int
+	first, last string
+}
+
+// indexCache keeps the indexes Contains builds for long lists.
+type indexCache[T setElement] struct {
+	mu      sync.Mutex
+	indexes map[listKey[T]]*listIndex
+}
+
+var (
+	memberIndexes = &indexCache[Member]{indexes: make(map[listKey[Member]]*listIndex)}
+	entityIndexes = &indexCache[Entity]{indexes: make(map[listKey[Entity]]*listIndex)}
+)
+
+// elementID returns the ID of v.
+func elementID[T setElement](v T) string {
+	return v.setKey(compareOptions{}).id
+}
+
+// contains reports whether list has an element with id. Short lists are scanned. Long
+// lists are looked up in a cached index, which is rebuilt when a hit is no longer at its
+// recorded position or the first or last element changed.
+func (c *indexCache[T]) contains(list []T, id string) bool {
+	if len(list) <= containsScanMax {
+		return slices.ContainsFunc(list, func(v T) bool { return elementID(v) == id })
+	}
+	key := listKey[T]{first: weak.Make(&list[0]), n: len(list)}
+	c.mu.Lock()
+	ix := c.indexes[key]
+	c.mu.Unlock()
+	if ix != nil && ix.first == elementID(list[0]) && ix.last == elementID(list[len(list)-1]) {
+		i, ok := ix.pos[id]
+		if !ok {
+			return false
+		}
+		if elementID(list[i]) == id {
+			return true
+		}
+	}
+
+	ix = &listIndex{pos: make(map[string]int, len(list)), first: elementID(list[0]), last: elementID(list[len(list)-1])}
+	for i, v := range list {
+		if _, dup := ix.pos[elementID(v)]; !dup {
+			ix.pos[elementID(v)] = i
+		}
+	}
+	c.mu.Lock()
+	if _, cached := c.indexes[key]; !cached {
+		runtime.AddCleanup(&list[0], c.forget, key)
+	}
+	c.indexes[key] = ix
+	c.mu.Unlock()
+	_, ok := ix.pos[id]
+	return ok
+}
+
+// forget drops the index cached under key.
+func (c *indexCache[T]) forget(key listKey[T]) {
+	c.mu.Lock()
+	delete(c.indexes, key)
+	c.mu.Unlock()
+}
+
+// Index returns an Index of the member IDs, for checking many IDs against the list.
+func (ml MemberList) Index(opts ...CompareOption) *Index {
+	return newIndex(ml, resolveCompareOptions(opts))
+}
+
+// Union returns a new MemberList of the members of ml followed by those of other not
+// in ml. Members are matched by type and ID, and duplicates are left out.
+func (ml MemberList) Union(other MemberList, opts ...CompareOption) *MemberList {
+	newList := MemberList(union(ml, other, resolveCompareOptions(opts)))
+	return &newList
+}
+
+// Intersect returns a new MemberList of the members of ml that are also in other.
+func (ml MemberList) Intersect(other MemberList, opts ...CompareOption) *MemberList {
+	o := resolveCompareOptions(opts)
+	newList := MemberList(selectKeys(ml, keySet(other, o), true, o))
+	return &newList
+}
+
+// Difference returns a new MemberList of the members of ml that are not in other.
+func (ml MemberList) Difference(other MemberList, opts ...CompareOption) *MemberList {
+	newList := MemberList(difference(ml, other, resolveCompareOptions(opts)))
+	return &newList
+}
+
+// SymmetricDifference returns a new MemberList of the members in only one of ml and
+// other, those of ml first.
+func (ml MemberList) SymmetricDifference(other MemberList, opts ...CompareOption) *MemberList {
+	o := resolveCompareOptions(opts)
+	newList := MemberList(append(difference(ml, other, o), difference(other, ml, o)...))
+	return &newList
+}
+
+// Dedupe modifies a MemberList, keeping only the first member with each type and ID.
+func (ml *MemberList) Dedupe(opts ...CompareOption) *MemberList {
+	*ml = selectKeys(*ml, nil, false, resolveCompareOptions(opts))
+	return ml
+}
+
+// Sort modifies a MemberList, ordering the members by type and then ID.
+func (ml *MemberList) Sort() *MemberList {
+	sortByKey(*ml)
+	return ml
+}
+
+// Index returns an Index of the entity IDs, for checking many IDs against the list.
+func (el EntityList) Index(opts ...CompareOption) *Index {
+	return newIndex(el, resolveCompareOptions(opts))
+}
+
+// Union returns a new EntityList of the entities of el followed by those of other not
+// in el. Entities are matched by type and ID, and duplicates are left out.
+func (el EntityList) Union(other EntityList, opts ...CompareOption) *EntityList {
+	newList := EntityList(union(el, other, resolveCompareOptions(opts)))
+	return &newList
+}
+
+// Intersect returns a new EntityList of the entities of el that are also in other.
+func (el EntityList) Intersect(other EntityList, opts ...CompareOption) *EntityList {
+	o := resolveCompareOptions(opts)
+	newList := EntityList(selectKeys(el, keySet(other, o), true, o))
+	return &newList
+}
+
+// Difference returns a new EntityList of the entities of el that are not in other.
+func (el EntityList) Difference(other EntityList, opts ...CompareOption) *EntityList {
+	newList := EntityList(difference(el, other, resolveCompareOptions(opts)))
+	return &newList
+}
+
+// SymmetricDifference returns a new EntityList of the entities in only one of el and
+// other, those of el first.
+func (el EntityList) SymmetricDifference(other EntityList, opts ...CompareOption) *EntityList {
+	o := resolveCompareOptions(opts)
+	newList := EntityList(append(difference(el, other, o), difference(other, el, o)...))
+	return &newList
+}
+
+// Dedupe modifies an EntityList, keeping only the first entity with each type and ID.
+func (el *EntityList) Dedupe(opts ...CompareOption) *EntityList {
+	*el = selectKeys(*el, nil, false, resolveCompareOptions(opts))
+	return el
+}
+
+// Sort modifies an EntityList, ordering the entities by type and then ID.
+func (el *EntityList) Sort() *EntityList {
+	sortByKey(*el)
+	return el
+}
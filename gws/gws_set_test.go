@@ -0,0 +1,197 @@
+package gws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// members returns a MemberList of UWNetIDs and groups, inferring the type from the ID.
+func members(ids ...string) MemberList {
+	ml := make(MemberList, 0, len(ids))
+	for _, id := range ids {
+		ml = append(ml, Member{Type: inferredMType(id), ID: id})
+	}
+	return ml
+}
+
+func TestMemberListSetOperations(t *testing.T) {
+	a := members("joeuser", "u_staff", "JaneUser", "joeuser")
+	b := members("janeuser", "u_staff", "u_faculty")
+	fold := []CompareOption{IgnoreUWNetIDCase()}
+
+	for _, tc := range []struct {
+		name string
+		op   func(a, b MemberList, opts ...CompareOption) *MemberList
+		opts []CompareOption
+		want MemberList
+	}{
+		{"Union", MemberList.Union, nil, members("joeuser", "u_staff", "JaneUser", "janeuser", "u_faculty")},
+		{"Union folded", MemberList.Union, fold, members("joeuser", "u_staff", "JaneUser", "u_faculty")},
+		{"Intersect", MemberList.Intersect, nil, members("u_staff")},
+		{"Intersect folded", MemberList.Intersect, fold, members("u_staff", "JaneUser")},
+		{"Difference", MemberList.Difference, nil, members("joeuser", "JaneUser")},
+		{"Difference folded", MemberList.Difference, fold, members("joeuser")},
+		{"SymmetricDifference", MemberList.SymmetricDifference, nil, members("joeuser", "JaneUser", "janeuser", "u_faculty")},
+		{"SymmetricDifference folded", MemberList.SymmetricDifference, fold, members("joeuser", "u_faculty")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.op(a, b, tc.opts...); !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("got %v, want %v", got.ToIDs(), tc.want.ToIDs())
+			}
+		})
+	}
+	if !reflect.DeepEqual(a, members("joeuser", "u_staff", "JaneUser", "joeuser")) {
+		t.Errorf("set operations changed their receiver to %v", a.ToIDs())
+	}
+}
+
+func TestEntityListSetOperations(t *testing.T) {
+	joe := Entity{Type: EntityTypeUWNetID, ID: "joeuser"}
+	bigJoe := Entity{Type: EntityTypeUWNetID, ID: "JoeUser"}
+	staff := Entity{Type: EntityTypeGroup, ID: "u_staff"}
+	// A group and a UWNetID with the same ID are different entities
+	staffUser := Entity{Type: EntityTypeUWNetID, ID: "u_staff"}
+	a := EntityList{joe, staff}
+	b := EntityList{bigJoe, staffUser}
+
+	for _, tc := range []struct {
+		name string
+		got  *EntityList
+		want EntityList
+	}{
+		{"Union", a.Union(b), EntityList{joe, staff, bigJoe, staffUser}},
+		{"Union folded", a.Union(b, IgnoreUWNetIDCase()), EntityList{joe, staff, staffUser}},
+		{"Intersect", a.Intersect(b), EntityList{}},
+		{"Intersect folded", a.Intersect(b, IgnoreUWNetIDCase()), EntityList{joe}},
+		{"Difference", a.Difference(b), EntityList{joe, staff}},
+		{"SymmetricDifference folded", a.SymmetricDifference(b, IgnoreUWNetIDCase()), EntityList{staff, staffUser}},
+	} {
+		if !reflect.DeepEqual(*tc.got, tc.want) {
+			t.Errorf("%s = %v, want %v", tc.name, *tc.got, tc.want)
+		}
+	}
+}
+
+func TestDedupeAndSort(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		list MemberList
+		opts []CompareOption
+		want MemberList
+	}{
+		{"empty", MemberList{}, nil, MemberList{}},
+		{"keeps first of each", members("u_b", "joeuser", "u_a", "joeuser", "u_b"), nil, members("u_b", "joeuser", "u_a")},
+		{"case kept apart", members("JoeUser", "joeuser"), nil, members("JoeUser", "joeuser")},
+		{"case folded", members("JoeUser", "joeuser", "u_a", "U_A"), []CompareOption{IgnoreUWNetIDCase()}, members("JoeUser", "u_a", "U_A")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.list.Dedupe(tc.opts...); !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("Dedupe = %v, want %v", got.ToIDs(), tc.want.ToIDs())
+			}
+		})
+	}
+
+	ml := members("u_b", "zed", "u_a", "amy", "Bob")
+	if got, want := ml.Sort().ToIDs(), []string{"u_a", "u_b", "Bob", "amy", "zed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort = %v, want groups then UWNetIDs, each by ID: %v", got, want)
+	}
+	el := EntityList{{Type: EntityTypeUWNetID, ID: "b"}, {Type: EntityTypeDNS, ID: "a.example.com"}, {Type: EntityTypeUWNetID, ID: "a"}}
+	if got, want := el.Sort().ToIDs(), []string{"a.example.com", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EntityList Sort = %v, want %v", got, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ml := members("JoeUser", "u_Staff", "janeuser")
+	for _, tc := range []struct {
+		name string
+		opts []CompareOption
+		id   string
+		want bool
+	}{
+		{"exact", nil, "JoeUser", true},
+		{"other case", nil, "joeuser", false},
+		{"missing", nil, "nobody", false},
+		{"folded UWNetID", []CompareOption{IgnoreUWNetIDCase()}, "JOEUSER", true},
+		{"folded exact", []CompareOption{IgnoreUWNetIDCase()}, "janeuser", true},
+		{"groups not folded", []CompareOption{IgnoreUWNetIDCase()}, "u_staff", false},
+		{"folded missing", []CompareOption{IgnoreUWNetIDCase()}, "nobody", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ml.Index(tc.opts...).Contains(tc.id); got != tc.want {
+				t.Errorf("Contains(%q) = %v, want %v", tc.id, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRemoveIDs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		list MemberList
+		ids  []string
+		want MemberList
+	}{
+		{"none", members("a", "b"), nil, members("a", "b")},
+		{"one", members("a", "b", "c"), []string{"b"}, members("a", "c")},
+		{"first of duplicates", members("a", "b", "a"), []string{"a"}, members("b", "a")},
+		{"each occurrence", members("a", "b", "a", "a"), []string{"a", "a"}, members("b", "a")},
+		{"more than present", members("a", "b"), []string{"a", "a", "a"}, members("b")},
+		{"missing", members("a"), []string{"z"}, members("a")},
+		{"all", members("a", "b"), []string{"b", "a"}, MemberList{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := len(tc.list)
+			got := MemberList(removeIDs(tc.list, tc.ids))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("removeIDs = %v, want %v", got.ToIDs(), tc.want.ToIDs())
+			}
+			// The removed tail of the backing array is cleared
+			for _, m := range tc.list[len(got):n] {
+				if m != (Member{}) {
+					t.Errorf("left %v in the backing array", m)
+				}
+			}
+		})
+	}
+}
+
+func TestContainsLongList(t *testing.T) {
+	ids := make([]string, 0, 3*containsScanMax)
+	for i := range cap(ids) {
+		ids = append(ids, fmt.Sprintf("user%d", i))
+	}
+	// Spare capacity keeps every change below in the same backing array
+	ml := append(make(MemberList, 0, len(ids)+8), members(ids...)...)
+	check := func(step string, want map[string]bool) {
+		t.Helper()
+		for id, in := range want {
+			if ml.Contains(id) != in {
+				t.Errorf("%s: Contains(%q) = %v, want %v", step, id, !in, in)
+			}
+		}
+	}
+
+	check("first call", map[string]bool{"user0": true, "user100": false, ids[len(ids)-1]: true})
+	ml.AppendMemberByID("newuser")
+	check("after append", map[string]bool{"newuser": true, "user0": true})
+	ml.RemoveMemberByID("user5", "newuser")
+	check("after remove", map[string]bool{"user5": false, "newuser": false, "user6": true})
+	// Back to the first call's length, so only the check of the last element notices
+	ml.AppendMemberByID("refill")
+	check("after refill", map[string]bool{"refill": true, "user5": false})
+	ml.Sort()
+	check("after sort", map[string]bool{"user7": true, "refill": true, "user5": false})
+	ml.Dedupe()
+	check("after dedupe", map[string]bool{"user7": true})
+
+	el := EntityList{}
+	for _, id := range ids {
+		el = append(el, Entity{Type: EntityTypeUWNetID, ID: id})
+	}
+	group := Group{Admins: el}
+	if !group.IsAdmin("user42") || group.IsAdmin("nobody") {
+		t.Error("IsAdmin on a long list gave the wrong answer")
+	}
+}
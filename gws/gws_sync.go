@@ -0,0 +1,141 @@
+package gws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrTooManyDeletes is returned by SyncMembership when the plan would remove more
+// members than SyncOptions.MaxDeletes allows. Nothing is changed in that case.
+var ErrTooManyDeletes = errors.New("gws: too many deletes")
+
+// NoDeleteLimit is the SyncOptions.MaxDeletes that lets a sync remove any number of members.
+const NoDeleteLimit = -1
+
+// MembershipPlan is the set of changes needed to bring a group's direct membership
+// in line with a desired MemberList.
+type MembershipPlan struct {
+	// Add are desired members that are not currently in the group
+	Add MemberList
+
+	// Remove are current members that are not desired
+	Remove MemberList
+
+	// Unchanged are members that are both current and desired
+	Unchanged MemberList
+}
+
+// SyncOptions controls how SyncMembership applies a plan.
+type SyncOptions struct {
+	// DryRun computes the plan without changing the group.
+	DryRun bool
+
+	// MaxDeletes refuses to apply a plan removing more than this many members. The zero
+	// value allows no removals at all; use NoDeleteLimit to allow any number.
+	MaxDeletes int
+
+	// Types restricts the sync to members of these types. Current members of other types
+	// are left alone and desired members of other types are ignored. Empty means all types.
+	Types []MemberType
+
+	// RequestOptions are passed to the add and delete requests.
+	RequestOptions []RequestOption
+}
+
+// SyncReport describes the outcome of SyncMembership.
+type SyncReport struct {
+	// Plan is the computed set of changes
+	Plan MembershipPlan
+
+	// NotFound are member IDs the API could not add because they do not exist
+	NotFound []string
+
+	// Applied is true once the plan has been carried out, false for a dry run or refusal
+	Applied bool
+}
+
+// PlanMembership compares current and desired membership by member ID and returns the
+// changes needed to go from one to the other. When types is not empty only members of
+// those types are considered.
+func PlanMembership(current, desired MemberList, types ...MemberType) MembershipPlan {
+	managed := func(m Member) bool {
+		return len(types) == 0 || slices.Contains(types, m.Type)
+	}
+
+	want := make(map[string]bool, len(desired))
+	for _, m := range desired {
+		if managed(m) {
+			want[m.ID] = true
+		}
+	}
+
+	var plan MembershipPlan
+	have := make(map[string]bool, len(current))
+	for _, m := range current {
+		have[m.ID] = true
+		if !managed(m) {
+			continue
+		}
+		if want[m.ID] {
+			plan.Unchanged = append(plan.Unchanged, m)
+		} else {
+			plan.Remove = append(plan.Remove, m)
+		}
+	}
+	for _, m := range desired {
+		if managed(m) && !have[m.ID] {
+			plan.Add = append(plan.Add, m)
+			have[m.ID] = true // skip duplicates in desired
+		}
+	}
+	return plan
+}
+
+// SyncMembership makes the direct membership of the group match desired using the fewest
+// add and delete calls, rather than replacing the whole membership as SetMembership does.
+// opts may be nil, which only adds members: a plan that removes any is refused.
+func (client *Client) SyncMembership(groupid string, desired MemberList, opts *SyncOptions) (*SyncReport, error) {
+	return client.SyncMembershipContext(context.Background(), groupid, desired, opts)
+}
+
+// SyncMembershipContext is like SyncMembership but carries ctx through to the API requests.
+func (client *Client) SyncMembershipContext(ctx context.Context, groupid string, desired MemberList, opts *SyncOptions) (*SyncReport, error) {
+	return invokeResult(client, ctx, "SyncMembership", groupid, func(ctx context.Context) (*SyncReport, error) {
+		if opts == nil {
+			opts = &SyncOptions{}
+		}
+
+		current, err := client.GetMembershipContext(ctx, groupid)
+		if err != nil {
+			return nil, err
+		}
+
+		report := &SyncReport{Plan: PlanMembership(*current, desired, opts.Types...)}
+		if opts.MaxDeletes != NoDeleteLimit && len(report.Plan.Remove) > max(opts.MaxDeletes, 0) {
+			return report, fmt.Errorf("%w: plan removes %d members of %s, limit is %d",
+				ErrTooManyDeletes, len(report.Plan.Remove), groupid, opts.MaxDeletes)
+		}
+		if opts.DryRun {
+			return report, nil
+		}
+
+		// Add before removing so the group never passes through a smaller membership than needed
+		if len(report.Plan.Add) > 0 {
+			notFound, err := client.AddMembersContext(ctx, groupid, report.Plan.Add.ToIDs(), opts.RequestOptions...)
+			// Chunks that did go through still report their not found IDs
+			report.NotFound = notFound
+			if err != nil {
+				return report, err
+			}
+		}
+		if len(report.Plan.Remove) > 0 {
+			if err := client.DeleteMembersContext(ctx, groupid, report.Plan.Remove.ToIDs(), opts.RequestOptions...); err != nil {
+				return report, err
+			}
+		}
+		report.Applied = true
+		return report, nil
+	})
+}
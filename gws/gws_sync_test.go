@@ -0,0 +1,164 @@
+package gws_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestSyncMembershipKeepsNotFoundOnPartialFailure(t *testing.T) {
+	srv := gwstest.NewServer()
+	defer srv.Close()
+	srv.AddGroup(gws.Group{ID: "u_sync"})
+
+	// Enough unknown IDs to need several chunks; the first chunk's request fails
+	var desired gws.MemberList
+	for i := range 400 {
+		desired = append(desired, gws.Member{Type: gws.MemberTypeUWNetID, ID: fmt.Sprintf("nobody%03d", i)})
+	}
+	var puts atomic.Int32
+	cfg := srv.Config()
+	cfg.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && puts.Add(1) == 1 {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"errors":[{"status":500}]}`)),
+				Request:    req,
+			}, nil
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	client, err := gws.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := client.SyncMembership("u_sync", desired, nil)
+	var chunkErr *gws.MemberChunkError
+	if !errors.As(err, &chunkErr) {
+		t.Fatalf("SyncMembership error = %v, want a MemberChunkError", err)
+	}
+	if puts.Load() < 2 {
+		t.Fatalf("sent %d PUTs, want the IDs split over several", puts.Load())
+	}
+	if report == nil || len(report.NotFound) == 0 {
+		t.Fatal("report.NotFound is empty, want the IDs reported by the chunks that succeeded")
+	}
+	for _, id := range report.NotFound {
+		if slices.Contains(chunkErr.MemberIDs, id) {
+			t.Errorf("NotFound has %s from the failed chunk", id)
+		}
+	}
+}
+
+func TestPlanMembership(t *testing.T) {
+	current := gws.MemberList{
+		{Type: gws.MemberTypeUWNetID, ID: "joeuser"},
+		{Type: gws.MemberTypeUWNetID, ID: "olduser"},
+		{Type: gws.MemberTypeGroup, ID: "u_inner"},
+	}
+	desired := gws.MemberList{
+		{Type: gws.MemberTypeUWNetID, ID: "joeuser"},
+		{Type: gws.MemberTypeUWNetID, ID: "newuser"},
+		{Type: gws.MemberTypeUWNetID, ID: "newuser"},
+		{Type: gws.MemberTypeEPPN, ID: "someone@example.edu"},
+	}
+	for _, tc := range []struct {
+		name                   string
+		types                  []gws.MemberType
+		add, remove, unchanged []string
+	}{
+		{"all types", nil, []string{"newuser", "someone@example.edu"}, []string{"olduser", "u_inner"}, []string{"joeuser"}},
+		{"uwnetid only", []gws.MemberType{gws.MemberTypeUWNetID}, []string{"newuser"}, []string{"olduser"}, []string{"joeuser"}},
+		{"group only", []gws.MemberType{gws.MemberTypeGroup}, nil, []string{"u_inner"}, nil},
+	} {
+		plan := gws.PlanMembership(current, desired, tc.types...)
+		if got := plan.Add.ToIDs(); !slices.Equal(got, tc.add) {
+			t.Errorf("%s: Add = %v, want %v", tc.name, got, tc.add)
+		}
+		if got := plan.Remove.ToIDs(); !slices.Equal(got, tc.remove) {
+			t.Errorf("%s: Remove = %v, want %v", tc.name, got, tc.remove)
+		}
+		if got := plan.Unchanged.ToIDs(); !slices.Equal(got, tc.unchanged) {
+			t.Errorf("%s: Unchanged = %v, want %v", tc.name, got, tc.unchanged)
+		}
+	}
+}
+
+// newSyncServer returns a server with u_sync holding joeuser, olduser and the group u_inner.
+func newSyncServer(t *testing.T) (*gwstest.Server, *gws.Client) {
+	t.Helper()
+	srv := gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPeople("joeuser", "olduser", "newuser")
+	srv.AddGroup(gws.Group{ID: "u_inner"})
+	srv.AddGroup(gws.Group{ID: "u_sync"},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "olduser"},
+		gws.Member{Type: gws.MemberTypeGroup, ID: "u_inner"})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestSyncMembership(t *testing.T) {
+	desired := gws.MemberList{}
+	desired.AppendMemberByID("joeuser", "newuser")
+
+	for _, tc := range []struct {
+		name    string
+		opts    *gws.SyncOptions
+		wantErr error
+		applied bool
+		members []string
+	}{
+		{"nil options refuse removals", nil, gws.ErrTooManyDeletes, false, []string{"joeuser", "olduser", "u_inner"}},
+		{"zero MaxDeletes refuses removals", &gws.SyncOptions{}, gws.ErrTooManyDeletes, false, []string{"joeuser", "olduser", "u_inner"}},
+		{"MaxDeletes below plan", &gws.SyncOptions{MaxDeletes: 1}, gws.ErrTooManyDeletes, false, []string{"joeuser", "olduser", "u_inner"}},
+		{"MaxDeletes equal to plan", &gws.SyncOptions{MaxDeletes: 2}, nil, true, []string{"joeuser", "newuser"}},
+		{"no limit", &gws.SyncOptions{MaxDeletes: gws.NoDeleteLimit}, nil, true, []string{"joeuser", "newuser"}},
+		{"dry run", &gws.SyncOptions{MaxDeletes: gws.NoDeleteLimit, DryRun: true}, nil, false, []string{"joeuser", "olduser", "u_inner"}},
+		{"uwnetid only", &gws.SyncOptions{MaxDeletes: 1, Types: []gws.MemberType{gws.MemberTypeUWNetID}}, nil, true, []string{"joeuser", "u_inner", "newuser"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, client := newSyncServer(t)
+
+			report, err := client.SyncMembership("u_sync", desired, tc.opts)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("SyncMembership error = %v, want %v", err, tc.wantErr)
+			}
+			if report == nil || report.Applied != tc.applied {
+				t.Fatalf("report = %+v, want Applied %v", report, tc.applied)
+			}
+			if len(report.Plan.Remove) == 0 || len(report.Plan.Add) != 1 {
+				t.Errorf("plan = %+v, want one add and some removals", report.Plan)
+			}
+			if got := srv.Members("u_sync").ToIDs(); !slices.Equal(got, tc.members) {
+				t.Errorf("members = %v, want %v", got, tc.members)
+			}
+			if !tc.applied {
+				for _, req := range srv.Requests() {
+					if req.Method != http.MethodGet {
+						t.Errorf("sent %s %s without applying the plan", req.Method, req.Path)
+					}
+				}
+			}
+		})
+	}
+}
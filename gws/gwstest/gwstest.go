@@ -0,0 +1,296 @@
+// Package gwstest provides an in-process fake of the UW Groups Service v3 API for use in tests.
+//
+// The fake keeps groups, memberships and history in memory and serves them over an
+// httptest.Server, so a gws.Client can be exercised without the real service:
+//
+//	srv := gwstest.NewServer()
+//	defer srv.Close()
+//	srv.AddPeople("joeuser", "janeuser")
+//	srv.AddGroup(gws.Group{ID: "u_test_group"}, gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"})
+//
+//	client, err := gws.NewClient(srv.Config())
+//
+// It reproduces the behaviors of the real service that clients have to cope with:
+// membership PUTs answer 200 with an error body listing the notFound IDs, group PUTs
+// check If-Match against the group ETag and fail with 412 on a mismatch, GETs honor
+// If-None-Match with 304, and effective membership is expanded through nested groups.
+// Group affiliates (email, google, radius) are stored per group as well.
+package gwstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+)
+
+// Server is a fake Groups Service backed by an in-memory model.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	groups   map[string]*group // by group id
+	regids   map[string]string // regid to group id
+	people   map[string]bool   // known uwnetids
+	nextID   int
+	requests []Request
+	faults   []fault
+}
+
+// Request records a request received by the fake server.
+type Request struct {
+	Method string
+
+	// Path and Query are escaped as they were sent
+	Path  string
+	Query string
+
+	Header http.Header
+}
+
+// group is the stored state of one group.
+type group struct {
+	data       gws.Group
+	members    gws.MemberList
+	affiliates map[gws.Affiliate]affiliate
+	version    int
+	history    []historyEntry
+}
+
+// affiliate is the stored state of one group affiliate.
+type affiliate struct {
+	Name   gws.Affiliate       `json:"name"`
+	Status gws.AffiliateStatus `json:"status"`
+	Sender string              `json:"sender,omitempty"`
+}
+
+// historyEntry is a history event with the activity class used for filtering.
+type historyEntry struct {
+	gws.HistoryEntry
+	class  string // acl or membership
+	member string
+}
+
+// fault is an injected failure for upcoming requests.
+type fault struct {
+	status int
+	count  int
+}
+
+// NewServer starts a fake Groups Service. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		groups: make(map[string]*group),
+		regids: make(map[string]string),
+		people: make(map[string]bool),
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// Config returns a gws.Config pointing at the fake server, with retries disabled.
+func (s *Server) Config() *gws.Config {
+	cfg := gws.DefaultConfig()
+	cfg.APIUrl = s.URL
+	cfg.Retry = nil
+	return cfg
+}
+
+// AddPeople registers UWNetIDs that exist. Membership changes naming any other
+// UWNetID report it as notFound, like the real service.
+func (s *Server) AddPeople(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.people[id] = true
+	}
+}
+
+// AddGroup stores a group and its direct members, replacing any existing group with the same ID.
+// A regid is assigned if the group has none.
+func (s *Server) AddGroup(g gws.Group, members ...gws.Member) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putGroup(g)
+	s.groups[g.ID].members = append(gws.MemberList{}, members...)
+}
+
+// Group returns a copy of the stored group.
+func (s *Server) Group(id string) (gws.Group, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return gws.Group{}, false
+	}
+	return g.data, true
+}
+
+// Members returns a copy of the stored direct membership of a group.
+func (s *Server) Members(id string) gws.MemberList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return nil
+	}
+	return append(gws.MemberList{}, g.members...)
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// FailNext makes the next count requests fail with the given HTTP status.
+func (s *Server) FailNext(count int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, fault{status: status, count: count})
+}
+
+// putGroup creates or replaces a group record. Caller holds s.mu.
+func (s *Server) putGroup(g gws.Group) *group {
+	now := time.Now().UnixMilli()
+	rec, ok := s.groups[g.ID]
+	if !ok {
+		if g.Regid == "" {
+			s.nextID++
+			g.Regid = fmt.Sprintf("%032x", s.nextID)
+		}
+		if g.Created == 0 {
+			g.Created = now
+		}
+		rec = &group{affiliates: make(map[gws.Affiliate]affiliate)}
+		s.groups[g.ID] = rec
+	} else {
+		g.Regid = rec.data.Regid
+		g.Created = rec.data.Created
+		g.LastMemberModified = rec.data.LastMemberModified
+	}
+	g.LastModified = now
+	rec.data = g
+	rec.version++
+	s.regids[g.Regid] = g.ID
+	return rec
+}
+
+// lookup resolves a group by id or regid. Caller holds s.mu.
+func (s *Server) lookup(id string) (*group, bool) {
+	if g, ok := s.groups[id]; ok {
+		return g, true
+	}
+	if gid, ok := s.regids[id]; ok {
+		g, ok := s.groups[gid]
+		return g, ok
+	}
+	return nil, false
+}
+
+// etag renders the current ETag of a group.
+func (g *group) etag() string {
+	return fmt.Sprintf(`"%s-%d"`, g.data.Regid, g.version)
+}
+
+// record appends a history event to the group. Caller holds s.mu.
+func (g *group) record(class, activity, member, description string) {
+	g.history = append(g.history, historyEntry{
+		HistoryEntry: gws.HistoryEntry{
+			Timestamp:   time.Now().UnixMilli(),
+			User:        "gwstest",
+			Activity:    activity,
+			Description: description,
+		},
+		class:  class,
+		member: member,
+	})
+}
+
+// memberType infers the type of a member ID the way the service does. Caller holds s.mu.
+func (s *Server) memberType(id string) gws.MemberType {
+	switch {
+	case strings.HasSuffix(id, "$"):
+		return gws.MemberTypeUWWI
+	case strings.Contains(id, "@"):
+		return gws.MemberTypeEPPN
+	case s.groups[id] != nil:
+		return gws.MemberTypeGroup
+	case strings.Contains(id, "."):
+		return gws.MemberTypeDNS
+	}
+	return gws.MemberTypeUWNetID
+}
+
+// exists reports whether a member can be added. Caller holds s.mu.
+func (s *Server) exists(m gws.Member) bool {
+	switch m.Type {
+	case gws.MemberTypeUWNetID:
+		return s.people[m.ID]
+	case gws.MemberTypeGroup:
+		return s.groups[m.ID] != nil
+	}
+	return true
+}
+
+// effective expands the membership of g through nested groups, returning each
+// non-group member once. Caller holds s.mu.
+func (s *Server) effective(g *group) gws.MemberList {
+	var out gws.MemberList
+	seen := make(map[gws.Member]bool)
+	visited := map[string]bool{g.data.ID: true}
+	var walk func(*group)
+	walk = func(g *group) {
+		for _, m := range g.members {
+			if m.Type == gws.MemberTypeGroup {
+				if sub, ok := s.groups[m.ID]; ok && !visited[m.ID] {
+					visited[m.ID] = true
+					walk(sub)
+				}
+				continue
+			}
+			if !seen[m] {
+				seen[m] = true
+				out = append(out, m)
+			}
+		}
+	}
+	walk(g)
+	return out
+}
+
+// via lists the groups under g through which id is an effective member. Caller holds s.mu.
+func (s *Server) via(g *group, id string) (direct bool, via []string) {
+	onPath := map[string]bool{g.data.ID: true}
+	var walk func(*group, string)
+	walk = func(cur *group, top string) {
+		for _, m := range cur.members {
+			if m.ID == id && m.Type != gws.MemberTypeGroup {
+				if top == "" {
+					direct = true
+				} else if !slices.Contains(via, top) {
+					via = append(via, top)
+				}
+			}
+			if m.Type == gws.MemberTypeGroup && !onPath[m.ID] {
+				if sub, ok := s.groups[m.ID]; ok {
+					onPath[m.ID] = true
+					next := top
+					if next == "" {
+						next = m.ID
+					}
+					walk(sub, next)
+					delete(onPath, m.ID)
+				}
+			}
+		}
+	}
+	walk(g, "")
+	return direct, via
+}
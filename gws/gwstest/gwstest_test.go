@@ -0,0 +1,154 @@
+package gwstest_test
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+)
+
+// newTestServer starts a fake with two people and a group u_test administered by admin1.
+func newTestServer(t *testing.T) (*gwstest.Server, *gws.Client) {
+	t.Helper()
+	srv := gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPeople("admin1", "joeuser")
+	srv.AddGroup(gws.Group{ID: "u_test", DisplayName: "Test", Admins: gws.EntityList{{Type: gws.EntityTypeUWNetID, ID: "admin1"}}})
+	client, err := gws.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestAddMembersReportsNotFound(t *testing.T) {
+	srv, client := newTestServer(t)
+
+	notFound, err := client.AddMembers("u_test", "joeuser", "nobody")
+	if err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	if !slices.Equal(notFound, []string{"nobody"}) {
+		t.Errorf("notFound = %v, want [nobody]", notFound)
+	}
+	if got := srv.Members("u_test").ToIDs(); !slices.Equal(got, []string{"joeuser"}) {
+		t.Errorf("members = %v, want [joeuser]", got)
+	}
+}
+
+func TestMemberIDWithComma(t *testing.T) {
+	srv, client := newTestServer(t)
+
+	notFound, err := client.AddMembers("u_test", "x,y@z.edu")
+	if err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+	if len(notFound) != 0 {
+		t.Errorf("notFound = %v, want none", notFound)
+	}
+	if got := srv.Members("u_test").ToIDs(); !slices.Equal(got, []string{"x,y@z.edu"}) {
+		t.Fatalf("members = %v, want [x,y@z.edu]", got)
+	}
+
+	if err := client.DeleteMembers("u_test", "x,y@z.edu"); err != nil {
+		t.Fatalf("DeleteMembers: %v", err)
+	}
+	if got := srv.Members("u_test"); len(got) != 0 {
+		t.Errorf("members after delete = %v, want none", got.ToIDs())
+	}
+}
+
+func TestUpdateGroupPreconditionFailed(t *testing.T) {
+	_, client := newTestServer(t)
+
+	first, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := first.Clone()
+
+	first.DisplayName = "First"
+	if _, err := client.UpdateGroup(first); err != nil {
+		t.Fatalf("first UpdateGroup: %v", err)
+	}
+	second.DisplayName = "Second"
+	_, err = client.UpdateGroup(second)
+	if !errors.Is(err, gws.ErrPreconditionFailed) {
+		t.Fatalf("second UpdateGroup error = %v, want ErrPreconditionFailed", err)
+	}
+}
+
+func TestGetGroupNotModified(t *testing.T) {
+	srv, client := newTestServer(t)
+
+	group, err := client.GetGroup("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/group/u_test", nil)
+	req.Header.Set("If-None-Match", group.ETag())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("status = %d, want 304", resp.StatusCode)
+	}
+
+	unchanged, changed, err := client.GetGroupIfChanged("u_test", group.ETag())
+	if err != nil || changed || unchanged != nil {
+		t.Errorf("GetGroupIfChanged(current ETag) = %v, %v, %v; want nil, false, nil", unchanged, changed, err)
+	}
+	fresh, changed, err := client.GetGroupIfChanged("u_test", `"stale"`)
+	if err != nil || !changed || fresh == nil || fresh.ETag() != group.ETag() {
+		t.Errorf("GetGroupIfChanged(stale ETag) = %v, %v, %v; want the group", fresh, changed, err)
+	}
+}
+
+func TestEffectiveMembershipNested(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.AddPeople("janeuser")
+	srv.AddGroup(gws.Group{ID: "u_inner"},
+		gws.Member{Type: gws.MemberTypeUWNetID, ID: "janeuser"},
+		gws.Member{Type: gws.MemberTypeGroup, ID: "u_test"}) // a cycle back to u_test
+	if _, err := client.AddMembers("u_test", "joeuser", "u_inner"); err != nil {
+		t.Fatal(err)
+	}
+
+	direct, err := client.GetMembership("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := direct.ToIDs(); !slices.Equal(got, []string{"joeuser", "u_inner"}) {
+		t.Errorf("direct members = %v, want [joeuser u_inner]", got)
+	}
+
+	effective, err := client.GetEffectiveMembership("u_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effective.ToIDs(); !slices.Equal(got, []string{"joeuser", "janeuser"}) {
+		t.Errorf("effective members = %v, want [joeuser janeuser]", got)
+	}
+
+	for _, tc := range []struct {
+		id              string
+		member, effMemb bool
+	}{
+		{"joeuser", true, true},
+		{"janeuser", false, true},
+		{"admin1", false, false},
+	} {
+		if got, err := client.IsMember("u_test", tc.id); err != nil || got != tc.member {
+			t.Errorf("IsMember(%s) = %v, %v; want %v", tc.id, got, err, tc.member)
+		}
+		if got, err := client.IsEffectiveMember("u_test", tc.id); err != nil || got != tc.effMemb {
+			t.Errorf("IsEffectiveMember(%s) = %v, %v; want %v", tc.id, got, err, tc.effMemb)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package gwstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+)
+
+const schema = "urn:mace:washington.edu:schemas:groups:1.0"
+
+// meta is the metadata block sent with every response.
+type meta struct {
+	ResourceType   string `json:"resourceType,omitempty"`
+	Version        string `json:"version"`
+	RegID          string `json:"regid,omitempty"`
+	ID             string `json:"id,omitempty"`
+	MembershipType string `json:"membershipType,omitempty"`
+	SelfRef        string `json:"selfRef,omitempty"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// response is the envelope of every successful response.
+type response struct {
+	Schemas []string `json:"schemas"`
+	Meta    meta     `json:"meta"`
+	Data    any      `json:"data,omitempty"`
+}
+
+// errorBody is the envelope of error responses and membership PUT results.
+type errorBody struct {
+	Schemas []string          `json:"schemas"`
+	Meta    meta              `json:"meta"`
+	Errors  []gws.ErrorDetail `json:"errors"`
+}
+
+// searchReference is one group in a search result.
+type searchReference struct {
+	Regid       string   `json:"regid"`
+	ID          string   `json:"id"`
+	DisplayName string   `json:"displayName"`
+	URL         string   `json:"url"`
+	Via         []string `json:"via,omitempty"`
+}
+
+// routes builds the handler for the v3 endpoints used by gws.Client.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /group/{id}", s.getGroup)
+	mux.HandleFunc("PUT /group/{id}", s.putGroupHandler)
+	mux.HandleFunc("DELETE /group/{id}", s.deleteGroup)
+	mux.HandleFunc("GET /group/{id}/history", s.getHistory)
+	mux.HandleFunc("GET /group/{id}/member", s.getMembers)
+	mux.HandleFunc("PUT /group/{id}/member", s.putMembers)
+	mux.HandleFunc("GET /group/{id}/member/{mid}", s.getMember)
+	mux.HandleFunc("PUT /group/{id}/member/{mids}", s.addMembers)
+	mux.HandleFunc("DELETE /group/{id}/member/{mids}", s.deleteMembers)
+	mux.HandleFunc("GET /group/{id}/effective_member", s.getEffectiveMembers)
+	mux.HandleFunc("GET /group/{id}/effective_member/{mid}", s.getEffectiveMember)
+	mux.HandleFunc("GET /group/{id}/affiliate/{name}", s.getAffiliate)
+	mux.HandleFunc("PUT /group/{id}/affiliate/{name}", s.putAffiliate)
+	mux.HandleFunc("DELETE /group/{id}/affiliate/{name}", s.deleteAffiliate)
+	mux.HandleFunc("GET /search", s.search)
+	mux.HandleFunc("PUT /groupMove/{regid}", s.moveGroup)
+	return s.intercept(mux)
+}
+
+// intercept records each request and applies any injected faults before routing.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.EscapedPath(),
+			Query:  r.URL.RawQuery,
+			Header: r.Header.Clone(),
+		})
+		status := 0
+		if len(s.faults) > 0 {
+			status = s.faults[0].status
+			if s.faults[0].count--; s.faults[0].count <= 0 {
+				s.faults = s.faults[1:]
+			}
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			writeError(w, status, http.StatusText(status))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && match == g.etag() {
+		w.Header().Set("ETag", g.etag())
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeGroup(w, http.StatusOK, g)
+}
+
+func (s *Server) putGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data gws.Group `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid group: "+err.Error())
+		return
+	}
+	id := r.PathValue("id")
+	body.Data.ID = id
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := http.StatusCreated
+	if g, ok := s.groups[id]; ok {
+		if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != g.etag() {
+			writeError(w, http.StatusPreconditionFailed, "Precondition failed: group has been modified")
+			return
+		}
+		status = http.StatusOK
+	} else if len(body.Data.Admins) == 0 {
+		writeError(w, http.StatusBadRequest, "Group must have at least one admin")
+		return
+	}
+	g := s.putGroup(body.Data)
+	if status == http.StatusCreated {
+		g.record("acl", "create group", "", "created group "+id)
+	} else {
+		g.record("acl", "update group", "", "updated group "+id)
+	}
+	s.writeGroup(w, status, g)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	delete(s.groups, g.data.ID)
+	delete(s.regids, g.data.Regid)
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("group", g)})
+}
+
+func (s *Server) getHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	q := r.URL.Query()
+	start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+	size, _ := strconv.Atoi(q.Get("size"))
+	entries := make([]gws.HistoryEntry, 0, len(g.history))
+	for _, e := range g.history {
+		if e.Timestamp < start {
+			continue
+		}
+		if a := q.Get("activity"); a != "" && a != e.class {
+			continue
+		}
+		if id := q.Get("id"); id != "" && id != e.member {
+			continue
+		}
+		entries = append(entries, e.HistoryEntry)
+	}
+	if q.Get("order") == "d" {
+		slices.Reverse(entries)
+	}
+	if size > 0 && len(entries) > size {
+		entries = entries[:size]
+	}
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("history", g), Data: entries})
+}
+
+func (s *Server) getMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	writeMembers(w, r, g, "direct", g.members)
+}
+
+func (s *Server) getEffectiveMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	writeMembers(w, r, g, "effective", s.effective(g))
+}
+
+func (s *Server) getMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	writeOneMember(w, g, "direct", g.members, r.PathValue("mid"))
+}
+
+func (s *Server) getEffectiveMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	writeOneMember(w, g, "effective", s.effective(g), r.PathValue("mid"))
+}
+
+// putMembers replaces the whole membership of a group.
+func (s *Server) putMembers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data gws.MemberList `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid membership: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	notFound := []string{}
+	members := gws.MemberList{}
+	for _, m := range body.Data {
+		if m.Type == gws.MemberTypeInvalid {
+			m.Type = s.memberType(m.ID)
+		}
+		if !s.exists(m) {
+			notFound = append(notFound, m.ID)
+			continue
+		}
+		if !hasMember(members, m.ID) {
+			members = append(members, gws.Member{Type: m.Type, ID: m.ID})
+		}
+	}
+	g.members = members
+	g.data.LastMemberModified = time.Now().UnixMilli()
+	g.record("membership", "replace members", "", "membership replaced")
+	writeNotFound(w, notFound)
+}
+
+// addMembers adds the comma separated member IDs in the path.
+func (s *Server) addMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	notFound := []string{}
+	for _, id := range memberIDs(r) {
+		m := gws.Member{Type: s.memberType(id), ID: id}
+		if !s.exists(m) {
+			notFound = append(notFound, id)
+			continue
+		}
+		if !hasMember(g.members, id) {
+			g.members = append(g.members, m)
+			g.record("membership", "add member", id, "added "+id)
+		}
+	}
+	g.data.LastMemberModified = time.Now().UnixMilli()
+	writeNotFound(w, notFound)
+}
+
+// deleteMembers removes the comma separated member IDs in the path.
+func (s *Server) deleteMembers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	for _, id := range memberIDs(r) {
+		before := len(g.members)
+		g.members = slices.DeleteFunc(g.members, func(m gws.Member) bool { return m.ID == id })
+		if len(g.members) != before {
+			g.record("membership", "delete member", id, "deleted "+id)
+		}
+	}
+	g.data.LastMemberModified = time.Now().UnixMilli()
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("groupmembers", g)})
+}
+
+func (s *Server) getAffiliate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	a, ok := g.affiliates[gws.Affiliate(r.PathValue("name"))]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Affiliate not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("affiliate", g), Data: a})
+}
+
+func (s *Server) putAffiliate(w http.ResponseWriter, r *http.Request) {
+	name := gws.Affiliate(r.PathValue("name"))
+	switch name {
+	case gws.AffiliateEmail, gws.AffiliateGoogle, gws.AffiliateUWNetID, gws.AffiliateRadius:
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid affiliate name")
+		return
+	}
+	q := r.URL.Query()
+	status := gws.AffiliateStatus(q.Get("status"))
+	if status != gws.AffiliateStatusActive && status != gws.AffiliateStatusInactive {
+		writeError(w, http.StatusBadRequest, "Invalid affiliate status")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	a := affiliate{Name: name, Status: status, Sender: q.Get("sender")}
+	g.affiliates[name] = a
+	g.record("acl", "update affiliate", "", "set affiliate "+string(name)+" "+string(status))
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("affiliate", g), Data: a})
+}
+
+func (s *Server) deleteAffiliate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	name := gws.Affiliate(r.PathValue("name"))
+	if _, ok := g.affiliates[name]; !ok {
+		writeError(w, http.StatusNotFound, "Affiliate not found")
+		return
+	}
+	delete(g.affiliates, name)
+	g.record("acl", "delete affiliate", "", "deleted affiliate "+string(name))
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: newMeta("affiliate", g)})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := q.Get("name")
+	stem := q.Get("stem")
+	scope := q.Get("scope")
+	member := q.Get("member")
+	owner := q.Get("owner")
+	effective := q.Get("type") == "effective"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := []searchReference{}
+	for _, g := range s.groups {
+		id := g.data.ID
+		if name != "" && !wildcardMatch(name, id) {
+			continue
+		}
+		if stem != "" {
+			rest, ok := strings.CutPrefix(id, stem+"_")
+			if !ok || (scope == "one" && strings.Contains(rest, "_")) {
+				continue
+			}
+		}
+		if owner != "" && !g.data.Admins.Contains(owner) && !g.data.Updaters.Contains(owner) && !g.data.Creators.Contains(owner) {
+			continue
+		}
+		if a := q.Get("affiliate"); a != "" {
+			if _, ok := g.affiliates[gws.Affiliate(a)]; !ok {
+				continue
+			}
+		}
+		if q.Get("instructor") != "" {
+			continue
+		}
+		ref := searchReference{Regid: g.data.Regid, ID: id, DisplayName: g.data.DisplayName, URL: s.URL + "/group/" + g.data.Regid}
+		if member != "" {
+			if !effective {
+				if !hasMember(g.members, member) {
+					continue
+				}
+			} else {
+				direct, via := s.via(g, member)
+				if !direct && len(via) == 0 {
+					continue
+				}
+				ref.Via = via
+			}
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].ID < refs[j].ID })
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: meta{ResourceType: "search", Version: "v3.0", Timestamp: time.Now().UnixMilli()}, Data: refs})
+}
+
+// moveGroup renames the leaf (newext) or the stem (newstem) of a group addressed by regid.
+func (s *Server) moveGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gid, ok := s.regids[r.PathValue("regid")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+	g := s.groups[gid]
+	stem, leaf := gid, ""
+	if i := strings.LastIndex(gid, "_"); i >= 0 {
+		stem, leaf = gid[:i], gid[i+1:]
+	}
+	q := r.URL.Query()
+	switch {
+	case q.Get("newext") != "":
+		leaf = q.Get("newext")
+	case q.Get("newstem") != "":
+		stem = q.Get("newstem")
+	default:
+		writeError(w, http.StatusBadRequest, "newext or newstem is required")
+		return
+	}
+	newID := stem + "_" + leaf
+	if _, exists := s.groups[newID]; exists && newID != gid {
+		writeError(w, http.StatusConflict, "Group "+newID+" already exists")
+		return
+	}
+	delete(s.groups, gid)
+	g.data.ID = newID
+	g.version++
+	s.groups[newID] = g
+	s.regids[g.data.Regid] = newID
+	for _, other := range s.groups {
+		for i, m := range other.members {
+			if m.Type == gws.MemberTypeGroup && m.ID == gid {
+				other.members[i].ID = newID
+			}
+		}
+	}
+	g.record("acl", "move group", "", "moved "+gid+" to "+newID)
+	s.writeGroup(w, http.StatusOK, g)
+}
+
+// writeGroup sends a group response with its ETag.
+func (s *Server) writeGroup(w http.ResponseWriter, status int, g *group) {
+	w.Header().Set("ETag", g.etag())
+	m := newMeta("group", g)
+	m.SelfRef = s.URL + "/group/" + g.data.Regid
+	writeJSON(w, status, response{Schemas: []string{schema}, Meta: m, Data: g.data})
+}
+
+// writeMembers sends a membership list, or its count for view=count.
+func writeMembers(w http.ResponseWriter, r *http.Request, g *group, mtype string, members gws.MemberList) {
+	m := newMeta("groupmembers", g)
+	m.MembershipType = mtype
+	if r.URL.Query().Get("view") == "count" {
+		writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: m, Data: map[string]int{"count": len(members)}})
+		return
+	}
+	if members == nil {
+		members = gws.MemberList{}
+	}
+	writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: m, Data: members})
+}
+
+// writeOneMember sends the named member of the list, or 404 if absent.
+func writeOneMember(w http.ResponseWriter, g *group, mtype string, members gws.MemberList, id string) {
+	for _, member := range members {
+		if member.ID == id {
+			m := newMeta("groupmembers", g)
+			m.MembershipType = mtype
+			writeJSON(w, http.StatusOK, response{Schemas: []string{schema}, Meta: m, Data: gws.MemberList{member}})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Member not found")
+}
+
+// writeNotFound sends the 200 "error" body that membership PUTs return.
+func writeNotFound(w http.ResponseWriter, notFound []string) {
+	writeJSON(w, http.StatusOK, errorBody{
+		Schemas: []string{schema},
+		Meta:    meta{Version: "v3.0", Timestamp: time.Now().UnixMilli()},
+		Errors:  []gws.ErrorDetail{{Status: http.StatusOK, NotFound: notFound}},
+	})
+}
+
+// writeError sends an API error response.
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, errorBody{
+		Schemas: []string{schema},
+		Meta:    meta{Version: "v3.0", Timestamp: time.Now().UnixMilli()},
+		Errors:  []gws.ErrorDetail{{Status: status, Detail: []string{detail}}},
+	})
+}
+
+// writeJSON encodes v as the response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newMeta(resourceType string, g *group) meta {
+	return meta{
+		ResourceType: resourceType,
+		Version:      "v3.0",
+		RegID:        g.data.Regid,
+		ID:           g.data.ID,
+		Timestamp:    time.Now().UnixMilli(),
+	}
+}
+
+// memberIDs returns the comma separated member IDs ending the request path. The path is
+// split before unescaping, so an escaped comma (%2C) stays inside its ID like it does for
+// the real service.
+func memberIDs(r *http.Request) []string {
+	p := r.URL.EscapedPath()
+	var ids []string
+	for _, raw := range strings.Split(p[strings.LastIndex(p, "/")+1:], ",") {
+		id, err := url.PathUnescape(raw)
+		if err != nil || id == "" {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// hasMember reports whether the list contains a member with the given ID.
+func hasMember(members gws.MemberList, id string) bool {
+	return slices.ContainsFunc(members, func(m gws.Member) bool { return m.ID == id })
+}
+
+// wildcardMatch matches a group id against a name pattern where "*" is a wildcard.
+func wildcardMatch(pattern, id string) bool {
+	ok, err := path.Match(pattern, id)
+	return err == nil && ok
+}
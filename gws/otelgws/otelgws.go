@@ -0,0 +1,171 @@
+// Package otelgws instruments a gws.Client with OpenTelemetry tracing and metrics.
+//
+// It is a separate module so the core client does not depend on OpenTelemetry.
+// Add the middleware through gws.Config or Client.Use:
+//
+//	mw, err := otelgws.NewMiddleware()
+//	if err != nil {
+//		return err
+//	}
+//	config.Middleware = append(config.Middleware, mw)
+//
+// Every client operation becomes a client span named after the operation, such as
+// "gws.GetGroup", with the group ID, last HTTP status and member count as attributes.
+// Operations made on behalf of another, like the GetMembership inside SyncMembership,
+// become child spans. The trace context is propagated to the Groups Service in the
+// request headers. Step errors that the parent turns into a result, like the member 404
+// inside IsMember, are not treated as failures.
+//
+// Two instruments are recorded per operation: the gws.client.operation.duration
+// histogram and the gws.client.operation.errors counter. Their attributes are the
+// operation, the HTTP status and, for failures, error.type. The group ID is left off
+// metrics to keep their cardinality bounded.
+package otelgws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/uwit-ue/uw-gws-client-go/gws/otelgws"
+
+// Attribute keys set on spans and metrics.
+const (
+	OperationKey   = attribute.Key("gws.operation")
+	GroupIDKey     = attribute.Key("gws.group.id")
+	MemberCountKey = attribute.Key("gws.member.count")
+	StatusCodeKey  = attribute.Key("http.response.status_code")
+	ErrorTypeKey   = attribute.Key("error.type")
+)
+
+// Option configures NewMiddleware.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the tracer provider, the global one by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithMeterProvider sets the meter provider, the global one by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = mp }
+}
+
+// WithPropagator sets the propagator used to send the trace context with each request,
+// the global one by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagator = p }
+}
+
+// middleware is the gws.Middleware returned by NewMiddleware.
+type middleware struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	duration   metric.Float64Histogram
+	errors     metric.Int64Counter
+}
+
+// NewMiddleware returns a gws.Middleware that traces and measures every client operation.
+func NewMiddleware(opts ...Option) (gws.Middleware, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(ScopeName)
+	duration, err := meter.Float64Histogram("gws.client.operation.duration",
+		metric.WithDescription("Duration of Groups Service client operations"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10, 30))
+	if err != nil {
+		return nil, err
+	}
+	errs, err := meter.Int64Counter("gws.client.operation.errors",
+		metric.WithDescription("Number of failed Groups Service client operations"),
+		metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &middleware{
+		tracer:     c.tracerProvider.Tracer(ScopeName),
+		propagator: c.propagator,
+		duration:   duration,
+		errors:     errs,
+	}, nil
+}
+
+// Wrap implements gws.Middleware.
+func (m *middleware) Wrap(next gws.Handler) gws.Handler {
+	return func(ctx context.Context, op *gws.Operation) error {
+		attrs := []attribute.KeyValue{OperationKey.String(op.Name)}
+		if op.GroupID != "" {
+			attrs = append(attrs, GroupIDKey.String(op.GroupID))
+		}
+		ctx, span := m.tracer.Start(ctx, "gws."+op.Name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...))
+		defer span.End()
+		m.propagator.Inject(ctx, propagation.HeaderCarrier(op.Header))
+
+		start := time.Now()
+		err := next(ctx, op)
+		elapsed := time.Since(start)
+
+		metricAttrs := []attribute.KeyValue{OperationKey.String(op.Name)}
+		if op.StatusCode != 0 {
+			span.SetAttributes(StatusCodeKey.Int(op.StatusCode))
+			metricAttrs = append(metricAttrs, StatusCodeKey.Int(op.StatusCode))
+		}
+		if op.MemberCount != 0 {
+			span.SetAttributes(MemberCountKey.Int(op.MemberCount))
+		}
+		if err != nil && !op.ErrorHandled(err) {
+			errType := ErrorTypeKey.String(errorType(err))
+			span.SetAttributes(errType)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			metricAttrs = append(metricAttrs, errType)
+			m.errors.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+		}
+		m.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(metricAttrs...))
+		return err
+	}
+}
+
+// errorType classifies err for the error.type attribute: the HTTP status for API errors,
+// the context error for cancellations and timeouts, and the Go type otherwise.
+func errorType(err error) string {
+	var apiErr *gws.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return fmt.Sprintf("%T", err)
+}
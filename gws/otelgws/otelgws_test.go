@@ -0,0 +1,197 @@
+package otelgws_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uwit-ue/uw-gws-client-go/gws"
+	"github.com/uwit-ue/uw-gws-client-go/gws/gwstest"
+	"github.com/uwit-ue/uw-gws-client-go/gws/otelgws"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// harness is a gwstest server and a client instrumented with recording providers.
+type harness struct {
+	srv    *gwstest.Server
+	client *gws.Client
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	srv := gwstest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddPeople("joeuser", "janeuser")
+	srv.AddGroup(gws.Group{ID: "u_test"}, gws.Member{Type: gws.MemberTypeUWNetID, ID: "joeuser"})
+
+	h := &harness{srv: srv, spans: tracetest.NewSpanRecorder(), reader: sdkmetric.NewManualReader()}
+	mw, err := otelgws.NewMiddleware(
+		otelgws.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(h.spans))),
+		otelgws.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(h.reader))),
+		otelgws.WithPropagator(propagation.TraceContext{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := srv.Config()
+	cfg.Middleware = []gws.Middleware{mw}
+	if h.client, err = gws.NewClient(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// span returns the one ended span with the given name.
+func (h *harness) span(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	var found []sdktrace.ReadOnlySpan
+	for _, s := range h.spans.Ended() {
+		if s.Name() == name {
+			found = append(found, s)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("found %d %s spans, want 1", len(found), name)
+	}
+	return found[0]
+}
+
+// metric returns the data of the named instrument, nil if nothing was recorded.
+func (h *harness) metric(t *testing.T, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := h.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	return nil
+}
+
+// checkAttrs reports the attributes of want that set lacks or holds with another value.
+func checkAttrs(t *testing.T, what string, set attribute.Set, want ...attribute.KeyValue) {
+	t.Helper()
+	for _, kv := range want {
+		if got, ok := set.Value(kv.Key); !ok || got != kv.Value {
+			t.Errorf("%s %s = %v, want %v", what, kv.Key, got.Emit(), kv.Value.Emit())
+		}
+	}
+}
+
+func TestSpanAndPropagation(t *testing.T) {
+	h := newHarness(t)
+
+	if _, err := h.client.GetGroup("u_test"); err != nil {
+		t.Fatal(err)
+	}
+	span := h.span(t, "gws.GetGroup")
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", span.SpanKind())
+	}
+	if span.Status().Code != codes.Unset {
+		t.Errorf("span status = %v, want unset", span.Status())
+	}
+	checkAttrs(t, "span", attribute.NewSet(span.Attributes()...),
+		otelgws.OperationKey.String("GetGroup"),
+		otelgws.GroupIDKey.String("u_test"),
+		otelgws.StatusCodeKey.Int(200))
+
+	reqs := h.srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(reqs))
+	}
+	carrier := propagation.HeaderCarrier(reqs[0].Header)
+	sent := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if sent.TraceID() != span.SpanContext().TraceID() || sent.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("traceparent = %q, want the GetGroup span", carrier.Get("traceparent"))
+	}
+
+	hist, ok := h.metric(t, "gws.client.operation.duration").(metricdata.Histogram[float64])
+	if !ok || len(hist.DataPoints) != 1 || hist.DataPoints[0].Count != 1 {
+		t.Fatalf("duration = %+v, want one GetGroup measurement", hist)
+	}
+	checkAttrs(t, "duration", hist.DataPoints[0].Attributes,
+		otelgws.OperationKey.String("GetGroup"),
+		otelgws.StatusCodeKey.Int(200))
+	if _, ok := hist.DataPoints[0].Attributes.Value(otelgws.GroupIDKey); ok {
+		t.Errorf("duration has a group ID attribute")
+	}
+	if errs := h.metric(t, "gws.client.operation.errors"); errs != nil {
+		t.Errorf("errors = %+v, want none", errs)
+	}
+}
+
+func TestChildSpans(t *testing.T) {
+	h := newHarness(t)
+
+	desired := gws.MemberList{}
+	desired.AppendMemberByID("joeuser", "janeuser")
+	if _, err := h.client.SyncMembership("u_test", desired, nil); err != nil {
+		t.Fatal(err)
+	}
+	parent := h.span(t, "gws.SyncMembership")
+	checkAttrs(t, "SyncMembership span", attribute.NewSet(parent.Attributes()...),
+		otelgws.StatusCodeKey.Int(200),
+		otelgws.MemberCountKey.Int(1))
+	for _, name := range []string{"gws.GetMembership", "gws.AddMembers"} {
+		child := h.span(t, name)
+		if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of gws.SyncMembership", name)
+		}
+	}
+}
+
+func TestHandledNotFound(t *testing.T) {
+	h := newHarness(t)
+
+	if ok, err := h.client.IsMember("u_test", "nobody"); err != nil || ok {
+		t.Fatalf("IsMember = %v, %v; want false", ok, err)
+	}
+	for _, name := range []string{"gws.IsMember", "gws.GetMember"} {
+		span := h.span(t, name)
+		if span.Status().Code == codes.Error || len(span.Events()) != 0 {
+			t.Errorf("%s status = %v with %d events, want no error", name, span.Status(), len(span.Events()))
+		}
+	}
+	checkAttrs(t, "GetMember span", attribute.NewSet(h.span(t, "gws.GetMember").Attributes()...),
+		otelgws.StatusCodeKey.Int(404))
+	if errs := h.metric(t, "gws.client.operation.errors"); errs != nil {
+		t.Errorf("errors = %+v, want none", errs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := newHarness(t)
+
+	if _, err := h.client.GetGroup("u_missing"); err == nil {
+		t.Fatal("GetGroup of a missing group succeeded")
+	}
+	span := h.span(t, "gws.GetGroup")
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want error", span.Status())
+	}
+	checkAttrs(t, "span", attribute.NewSet(span.Attributes()...),
+		otelgws.StatusCodeKey.Int(404),
+		otelgws.ErrorTypeKey.String("404"))
+
+	sum, ok := h.metric(t, "gws.client.operation.errors").(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 1 {
+		t.Fatalf("errors = %+v, want one", sum)
+	}
+	checkAttrs(t, "errors", sum.DataPoints[0].Attributes,
+		otelgws.OperationKey.String("GetGroup"),
+		otelgws.StatusCodeKey.Int(404),
+		otelgws.ErrorTypeKey.String("404"))
+}